@@ -3,6 +3,13 @@ module github.com/Archer1A/go-kits
 go 1.21
 
 require (
+	github.com/klauspost/compress v1.17.11
+	github.com/stretchr/testify v1.3.0
 	go.uber.org/atomic v1.9.0
 	golang.org/x/time v0.0.0-20211116232009-f0f3c7e86c11
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+)
@@ -0,0 +1,232 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type LoadBalancePolicy int
+
+const (
+	RoundRobin LoadBalancePolicy = iota
+	Random
+	LeastInFlight
+	ConsistentHash
+)
+
+const hashReplicas = 100
+
+// OutlierDetection passively ejects endpoints that fail repeatedly.
+// A request is failed if the transport returns an error or the response status code is 5xx.
+type OutlierDetection struct {
+	// ConsecutiveFailures is the number of failures in a row that ejects the endpoint. Default is 5.
+	ConsecutiveFailures int
+	// EjectionTime is how long an ejected endpoint is kept out of balancing. Default is 30 seconds.
+	EjectionTime time.Duration
+}
+
+type LoadBalanceConfig struct {
+	Policy LoadBalancePolicy
+	// HashKey returns the key to pick endpoint when Policy is ConsistentHash.
+	HashKey          BucketGetter
+	OutlierDetection OutlierDetection
+}
+
+type endpointStats struct {
+	inFlight     int
+	consecutive  int
+	ejectedUntil time.Time
+}
+
+type hashRing struct {
+	signature string
+	hashes    []uint32
+	endpoints map[uint32]string
+}
+
+type loadBalancer struct {
+	// name is the name of Service, or its Hosts joined by comma.
+	name      string
+	config    LoadBalanceConfig
+	endpoints func(ctx context.Context) ([]string, error)
+	mu        sync.Mutex
+	next      int
+	stats     map[string]*endpointStats
+	ring      *hashRing
+}
+
+func newLoadBalancer(config LoadBalanceConfig, endpoints func(ctx context.Context) ([]string, error)) *loadBalancer {
+	if config.OutlierDetection.ConsecutiveFailures <= 0 {
+		config.OutlierDetection.ConsecutiveFailures = 5
+	}
+	if config.OutlierDetection.EjectionTime <= 0 {
+		config.OutlierDetection.EjectionTime = 30 * time.Second
+	}
+	if config.Policy == ConsistentHash && config.HashKey == nil {
+		config.HashKey = func(ctx *Context) interface{} {
+			return ctx.Request.Path
+		}
+	}
+	return &loadBalancer{
+		config:    config,
+		endpoints: endpoints,
+		stats:     make(map[string]*endpointStats),
+	}
+}
+
+// pick chooses an endpoint for the current attempt of Request.
+// The returned function must be called with the result of the attempt, err and rsp are both nil if
+// the HTTP request was never sent.
+func (lb *loadBalancer) pick(ctx *Context) (string, func(err error, rsp *http.Response), error) {
+	c := ctx.Context
+	if c == nil {
+		c = context.Background()
+	}
+	endpoints, err := lb.endpoints(c)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(endpoints) == 0 {
+		return "", nil, errors.New("no endpoint available")
+	}
+	var key string
+	if lb.config.Policy == ConsistentHash {
+		key = fmt.Sprintf("%v", lb.config.HashKey(ctx))
+	}
+
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	now := time.Now()
+	healthy := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if stats, ok := lb.stats[endpoint]; !ok || !now.Before(stats.ejectedUntil) {
+			healthy = append(healthy, endpoint)
+		}
+	}
+	if len(healthy) == 0 {
+		// every endpoint is ejected, balance among all of them rather than failing the request
+		healthy = endpoints
+	}
+
+	var endpoint string
+	switch lb.config.Policy {
+	case Random:
+		endpoint = healthy[rand.Intn(len(healthy))]
+	case LeastInFlight:
+		lb.next++
+		for i := range healthy {
+			candidate := healthy[(lb.next+i)%len(healthy)]
+			if endpoint == "" || lb.statsOf(candidate).inFlight < lb.statsOf(endpoint).inFlight {
+				endpoint = candidate
+			}
+		}
+	case ConsistentHash:
+		endpoint = lb.hash(endpoints, healthy, key)
+	default:
+		endpoint = healthy[lb.next%len(healthy)]
+		lb.next++
+	}
+
+	lb.statsOf(endpoint).inFlight++
+	return endpoint, func(err error, rsp *http.Response) {
+		lb.release(endpoint, err, rsp)
+	}, nil
+}
+
+func (lb *loadBalancer) release(endpoint string, err error, rsp *http.Response) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	stats := lb.statsOf(endpoint)
+	stats.inFlight--
+	if (err == nil && rsp == nil) || errors.Is(err, context.Canceled) {
+		return
+	}
+	if err == nil && rsp.StatusCode < http.StatusInternalServerError {
+		stats.consecutive = 0
+		return
+	}
+	stats.consecutive++
+	if stats.consecutive >= lb.config.OutlierDetection.ConsecutiveFailures {
+		stats.consecutive = 0
+		stats.ejectedUntil = time.Now().Add(lb.config.OutlierDetection.EjectionTime)
+	}
+}
+
+func (lb *loadBalancer) statsOf(endpoint string) *endpointStats {
+	stats, ok := lb.stats[endpoint]
+	if !ok {
+		stats = &endpointStats{}
+		lb.stats[endpoint] = stats
+	}
+	return stats
+}
+
+// hash walks the ring built from all endpoints, so that keys keep their endpoints while others are ejected.
+func (lb *loadBalancer) hash(endpoints, healthy []string, key string) string {
+	signature := strings.Join(endpoints, ",")
+	if lb.ring == nil || lb.ring.signature != signature {
+		ring := &hashRing{signature: signature, endpoints: make(map[uint32]string)}
+		for _, endpoint := range endpoints {
+			for i := 0; i < hashReplicas; i++ {
+				h := hashOf(strconv.Itoa(i) + endpoint)
+				ring.hashes = append(ring.hashes, h)
+				ring.endpoints[h] = endpoint
+			}
+		}
+		sort.Slice(ring.hashes, func(i, j int) bool {
+			return ring.hashes[i] < ring.hashes[j]
+		})
+		lb.ring = ring
+	}
+	available := make(map[string]struct{}, len(healthy))
+	for _, endpoint := range healthy {
+		available[endpoint] = struct{}{}
+	}
+	h := hashOf(key)
+	start := sort.Search(len(lb.ring.hashes), func(i int) bool {
+		return lb.ring.hashes[i] >= h
+	})
+	for i := 0; i < len(lb.ring.hashes); i++ {
+		endpoint := lb.ring.endpoints[lb.ring.hashes[(start+i)%len(lb.ring.hashes)]]
+		if _, ok := available[endpoint]; ok {
+			return endpoint
+		}
+	}
+	return healthy[0]
+}
+
+func hashOf(key string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum32()
+}
+
+// resolvedHosts returns the endpoints of service from resolver as host names of Request.
+func resolvedHosts(ctx context.Context, resolver Resolver, name string, port uint, secure bool) ([]string, error) {
+	scheme := schemeHttp
+	if secure {
+		scheme = schemeHttps
+	}
+	if port == 0 {
+		port = defaultPort(scheme)
+	}
+	endpoints, err := resolver.Resolve(ctx, name, port)
+	if err != nil {
+		return nil, err
+	}
+	hosts := make([]string, len(endpoints))
+	for i, endpoint := range endpoints {
+		hosts[i] = (&url.URL{Scheme: scheme, Host: endpoint.String()}).String()
+	}
+	return hosts, nil
+}
@@ -0,0 +1,186 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLoadBalancerPick(t *testing.T) {
+	hosts := []string{"http://a", "http://b", "http://c"}
+	static := func(ctx context.Context) ([]string, error) {
+		return hosts, nil
+	}
+	pick := func(lb *loadBalancer, path string) (string, func(err error, rsp *http.Response)) {
+		ctx := Req().WithPath(path).ctx
+		host, release, err := lb.pick(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return host, release
+	}
+
+	tests := []struct {
+		name   string
+		config LoadBalanceConfig
+		run    func(lb *loadBalancer) bool
+	}{
+		{
+			name:   "round robin",
+			config: LoadBalanceConfig{Policy: RoundRobin},
+			run: func(lb *loadBalancer) bool {
+				for i := 0; i < 6; i++ {
+					host, release := pick(lb, "")
+					release(nil, &http.Response{StatusCode: http.StatusOK})
+					if host != hosts[i%len(hosts)] {
+						return false
+					}
+				}
+				return true
+			},
+		},
+		{
+			name:   "random",
+			config: LoadBalanceConfig{Policy: Random},
+			run: func(lb *loadBalancer) bool {
+				for i := 0; i < 10; i++ {
+					host, release := pick(lb, "")
+					release(nil, nil)
+					if host != "http://a" && host != "http://b" && host != "http://c" {
+						return false
+					}
+				}
+				return true
+			},
+		},
+		{
+			name:   "least in flight",
+			config: LoadBalanceConfig{Policy: LeastInFlight},
+			run: func(lb *loadBalancer) bool {
+				picked := make(map[string]struct{})
+				for i := 0; i < 3; i++ {
+					host, _ := pick(lb, "")
+					picked[host] = struct{}{}
+				}
+				return len(picked) == 3
+			},
+		},
+		{
+			name:   "consistent hash",
+			config: LoadBalanceConfig{Policy: ConsistentHash},
+			run: func(lb *loadBalancer) bool {
+				first, release := pick(lb, "users/1")
+				release(nil, nil)
+				for i := 0; i < 10; i++ {
+					host, release := pick(lb, "users/1")
+					release(nil, nil)
+					if host != first {
+						return false
+					}
+				}
+				return true
+			},
+		},
+		{
+			name:   "outlier ejected",
+			config: LoadBalanceConfig{OutlierDetection: OutlierDetection{ConsecutiveFailures: 2, EjectionTime: time.Minute}},
+			run: func(lb *loadBalancer) bool {
+				for i := 0; i < 6; i++ {
+					host, release := pick(lb, "")
+					if host == "http://a" {
+						release(errors.New("connection refused"), nil)
+					} else {
+						release(nil, &http.Response{StatusCode: http.StatusOK})
+					}
+				}
+				for i := 0; i < 6; i++ {
+					host, release := pick(lb, "")
+					release(nil, nil)
+					if host == "http://a" {
+						return false
+					}
+				}
+				return true
+			},
+		},
+		{
+			name:   "consistent hash skips ejected",
+			config: LoadBalanceConfig{Policy: ConsistentHash, OutlierDetection: OutlierDetection{ConsecutiveFailures: 1}},
+			run: func(lb *loadBalancer) bool {
+				first, release := pick(lb, "users/1")
+				release(nil, &http.Response{StatusCode: http.StatusBadGateway})
+				host, release := pick(lb, "users/1")
+				release(nil, nil)
+				return host != first
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.run(newLoadBalancer(tt.config, static)) {
+				t.Error("loadBalancer.pick() test failed")
+			}
+		})
+	}
+}
+
+func TestServiceLoadBalance(t *testing.T) {
+	var servers []*httptest.Server
+	hits := make(map[string]int)
+	for i := 0; i < 2; i++ {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{}`))
+		}))
+		defer server.Close()
+		servers = append(servers, server)
+	}
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+
+	service := &Service{
+		Hosts:       []string{servers[0].URL, servers[1].URL, down.URL},
+		LoadBalance: LoadBalanceConfig{OutlierDetection: OutlierDetection{ConsecutiveFailures: 1}},
+	}
+	var failures int
+	for i := 0; i < 12; i++ {
+		req := service.Serve().WithPath("lb")
+		rsp := &DefaultResponse{Data: &map[string]interface{}{}}
+		req.Get(rsp)
+		if rsp.Error() != nil {
+			failures++
+			continue
+		}
+		hits[req.HostName]++
+	}
+	if failures != 1 {
+		t.Errorf("Service.Serve() failures = %d, want 1", failures)
+	}
+	if hits[servers[0].URL] == 0 || hits[servers[1].URL] == 0 {
+		t.Errorf("Service.Serve() hits = %v, want both servers", hits)
+	}
+
+	// copies share the balancer, so the ejected endpoint is still skipped
+	copied := *service
+	for i := 0; i < 3; i++ {
+		rsp := &DefaultResponse{Data: &map[string]interface{}{}}
+		if copied.Serve().WithPath("lb").Get(rsp); rsp.Error() != nil {
+			t.Errorf("copied Service.Serve() error = %v, want ejected endpoint skipped", rsp.Error())
+		}
+	}
+	if copied.Serve().balancer != service.Serve().balancer {
+		t.Errorf("copied Service.Serve() balancer is not shared")
+	}
+
+	// a copy with other endpoints has its own balancer, and the original keeps its endpoints
+	copied.Hosts = []string{servers[1].URL}
+	req := copied.Serve().WithPath("lb")
+	if req.Get(&DefaultResponse{Data: &map[string]interface{}{}}); req.HostName != servers[1].URL {
+		t.Errorf("copied Service.Serve() host = %s, want %s", req.HostName, servers[1].URL)
+	}
+	if copied.Serve().balancer == service.Serve().balancer {
+		t.Errorf("copied Service.Serve() balancer is shared after Hosts changed")
+	}
+}
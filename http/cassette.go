@@ -0,0 +1,331 @@
+package http
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"unicode/utf8"
+)
+
+// CassetteInteractionNotFoundError is returned by Cassette in replay mode if no recorded interaction matches the
+// request.
+var CassetteInteractionNotFoundError = errors.New("no interaction recorded in cassette matches the request")
+
+type CassetteMode int
+
+const (
+	// CassetteReplay serves the recorded interactions, requests are never sent.
+	CassetteReplay CassetteMode = iota
+	// CassetteRecord sends requests by Transport and records the interactions, the cassette file is overwritten.
+	CassetteRecord
+)
+
+// bodyEncodingBase64 marks the bodies which are not valid UTF-8.
+const bodyEncodingBase64 = "base64"
+
+type CassetteRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+	// Encoding is base64 if Body is encoded in base64.
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type CassetteResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	Encoding   string      `json:"encoding,omitempty"`
+}
+
+type CassetteInteraction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+type cassetteFile struct {
+	Interactions []*CassetteInteraction `json:"interactions"`
+}
+
+// CassetteMatcher reports whether req matches the recorded request, req is redacted the same way as the recorded one.
+type CassetteMatcher func(req, recorded *CassetteRequest) bool
+
+var (
+	MatchMethod CassetteMatcher = func(req, recorded *CassetteRequest) bool {
+		return req.Method == recorded.Method
+	}
+	// MatchPath ignores the host, so that interactions recorded from one endpoint of Service could be replayed for
+	// the others.
+	MatchPath CassetteMatcher = func(req, recorded *CassetteRequest) bool {
+		u1, err1 := url.Parse(req.URL)
+		u2, err2 := url.Parse(recorded.URL)
+		return err1 == nil && err2 == nil && u1.EscapedPath() == u2.EscapedPath()
+	}
+	// MatchQuery ignores the order of query keys.
+	MatchQuery CassetteMatcher = func(req, recorded *CassetteRequest) bool {
+		u1, err1 := url.Parse(req.URL)
+		u2, err2 := url.Parse(recorded.URL)
+		return err1 == nil && err2 == nil && u1.Query().Encode() == u2.Query().Encode()
+	}
+	MatchBody CassetteMatcher = func(req, recorded *CassetteRequest) bool {
+		return req.Body == recorded.Body && req.Encoding == recorded.Encoding
+	}
+)
+
+// DefaultCassetteMatchers match requests by method, path and query.
+var DefaultCassetteMatchers = []CassetteMatcher{MatchMethod, MatchPath, MatchQuery}
+
+type CassetteConfig struct {
+	Mode CassetteMode
+	// Transport sends the requests in record mode, http.DefaultTransport is used if it is nil.
+	Transport http.RoundTripper
+	// Matchers decide which recorded interaction serves the request in replay mode, all of them should match.
+	// Default is DefaultCassetteMatchers.
+	Matchers []CassetteMatcher
+	// Redaction masks the headers, queries and fields of JSON and form bodies before they are saved, headers in
+	// DefaultRedactedHeaders are always masked. Requests are redacted as well before being matched in replay mode.
+	Redaction Redaction
+	// Replayable allows an interaction to be replayed more than once, otherwise every interaction serves one request
+	// in the order they are recorded.
+	Replayable bool
+}
+
+// Cassette is an http.RoundTripper which records real interactions into a JSON file, and replays them in tests, so
+// that the tests are deterministic and do not need live endpoints. Use Client as Request.Client or Service.Client.
+//
+// Response bodies are saved decompressed, since they are decompressed by Client anyway.
+type Cassette struct {
+	path         string
+	config       CassetteConfig
+	mu           sync.Mutex
+	interactions []*CassetteInteraction
+	used         []bool
+}
+
+// NewCassette returns a Cassette of the file at path, which is loaded in replay mode.
+func NewCassette(path string, config CassetteConfig) (*Cassette, error) {
+	if config.Transport == nil {
+		config.Transport = http.DefaultTransport
+	}
+	if len(config.Matchers) == 0 {
+		config.Matchers = DefaultCassetteMatchers
+	}
+	c := &Cassette{path: path, config: config}
+	if config.Mode != CassetteReplay {
+		return c, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file cassetteFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("malformed cassette file %s: %v", path, err)
+	}
+	c.interactions, c.used = file.Interactions, make([]bool, len(file.Interactions))
+	return c, nil
+}
+
+// Client returns an http.Client which sends requests by the Cassette.
+func (c *Cassette) Client() *http.Client {
+	return &http.Client{Transport: c}
+}
+
+// Interactions returns the interactions recorded or loaded.
+func (c *Cassette) Interactions() []*CassetteInteraction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*CassetteInteraction(nil), c.interactions...)
+}
+
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recordedReq := c.redactRequest(req, body)
+	if c.config.Mode == CassetteReplay {
+		return c.replay(req, recordedReq)
+	}
+
+	sent := req.Clone(req.Context())
+	if body != nil {
+		sent.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	rsp, err := c.config.Transport.RoundTrip(sent)
+	if err != nil {
+		return nil, err
+	}
+	if err = decodeResponse(rsp); err != nil {
+		_ = rsp.Body.Close()
+		return nil, err
+	}
+	rspBody, err := ioutil.ReadAll(rsp.Body)
+	_ = rsp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	rsp.Body = ioutil.NopCloser(bytes.NewReader(rspBody))
+	rsp.ContentLength = int64(len(rspBody))
+
+	interaction := &CassetteInteraction{
+		Request: *recordedReq,
+		Response: CassetteResponse{
+			StatusCode: rsp.StatusCode,
+			Header:     c.config.Redaction.header(rsp.Header),
+		},
+	}
+	interaction.Response.Body, interaction.Response.Encoding = encodeCassetteBody(
+		c.redactBody(rspBody, rsp.Header.Get(ContentTypeHeader)))
+	if err = c.record(interaction); err != nil {
+		_ = rsp.Body.Close()
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// readRequestBody reads and closes the body of req.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	defer func() {
+		_ = req.Body.Close()
+	}()
+	return ioutil.ReadAll(req.Body)
+}
+
+func (c *Cassette) redactRequest(req *http.Request, body []byte) *CassetteRequest {
+	u := *req.URL
+	if u.RawQuery != "" {
+		u.RawQuery = c.config.Redaction.values(u.Query(), c.config.Redaction.Queries).Encode()
+	}
+	recorded := &CassetteRequest{
+		Method: req.Method,
+		URL:    u.String(),
+		Header: c.config.Redaction.header(req.Header),
+	}
+	recorded.Body, recorded.Encoding = encodeCassetteBody(c.redactBody(body, req.Header.Get(ContentTypeHeader)))
+	return recorded
+}
+
+// redactBody masks the Fields of JSON and form bodies, others are saved as they are. Bodies are kept untouched if
+// there is no field to be masked, since masking reformats them.
+func (c *Cassette) redactBody(body []byte, contentType string) []byte {
+	if len(c.config.Redaction.Fields) == 0 {
+		return body
+	}
+	if masked, ok := c.config.Redaction.maskBody(body, contentType); ok {
+		return masked
+	}
+	return body
+}
+
+func encodeCassetteBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), bodyEncodingBase64
+}
+
+func decodeCassetteBody(body, encoding string) ([]byte, error) {
+	if encoding == bodyEncodingBase64 {
+		return base64.StdEncoding.DecodeString(body)
+	}
+	return []byte(body), nil
+}
+
+func (c *Cassette) replay(req *http.Request, recordedReq *CassetteRequest) (*http.Response, error) {
+	interaction, err := c.match(recordedReq)
+	if err != nil {
+		return nil, err
+	}
+	body, err := decodeCassetteBody(interaction.Response.Body, interaction.Response.Encoding)
+	if err != nil {
+		return nil, fmt.Errorf("malformed body in cassette file %s: %v", c.path, err)
+	}
+	header := http.Header{}
+	for key, values := range interaction.Response.Header {
+		header[key] = append([]string(nil), values...)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+		StatusCode:    interaction.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// match returns the first unused interaction which matches req, or the first used one if Replayable.
+func (c *Cassette) match(req *CassetteRequest) (*CassetteInteraction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	replayed := -1
+	for i, interaction := range c.interactions {
+		if !c.matches(req, &interaction.Request) {
+			continue
+		}
+		if !c.used[i] {
+			c.used[i] = true
+			return interaction, nil
+		}
+		if replayed < 0 {
+			replayed = i
+		}
+	}
+	if replayed >= 0 && c.config.Replayable {
+		return c.interactions[replayed], nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", CassetteInteractionNotFoundError, req.Method, req.URL)
+}
+
+func (c *Cassette) matches(req, recorded *CassetteRequest) bool {
+	for _, matcher := range c.config.Matchers {
+		if !matcher(req, recorded) {
+			return false
+		}
+	}
+	return true
+}
+
+// record appends the interaction and saves all of them, the file is replaced at once so that it is never partially
+// written.
+func (c *Cassette) record(interaction *CassetteInteraction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, interaction)
+	c.used = append(c.used, false)
+	data, err := json.MarshalIndent(cassetteFile{Interactions: c.interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
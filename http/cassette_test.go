@@ -0,0 +1,182 @@
+package http
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type cassetteUser struct {
+	Name     string `json:"name"`
+	Password string `json:"password,omitempty"`
+	Token    string `json:"token,omitempty"`
+}
+
+type cassetteQuery struct {
+	Page  int    `query:"page"`
+	Token string `query:"token"`
+}
+
+func TestCassette(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users":
+			user := cassetteUser{}
+			_ = json.NewDecoder(r.Body).Decode(&user)
+			w.Header().Set(ContentTypeHeader, ContentTypeJson)
+			w.Header().Set("Set-Cookie", "session=abc")
+			w.Header().Set(ContentEncodingHeader, EncodingGzip)
+			gz := gzip.NewWriter(w)
+			_ = json.NewEncoder(gz).Encode(cassetteUser{Name: user.Name, Token: "t0ken"})
+			_ = gz.Close()
+		case "/avatar":
+			_, _ = w.Write([]byte{0xff, 0xd8, 0xff})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	path := filepath.Join(t.TempDir(), "users.json")
+	redaction := Redaction{
+		Queries: map[string]Mask{"token": nil},
+		Fields:  map[string]Mask{"password": nil, "token": nil},
+	}
+	recorder, err := NewCassette(path, CassetteConfig{Mode: CassetteRecord, Redaction: redaction})
+	if err != nil {
+		t.Fatalf("NewCassette() error = %v", err)
+	}
+	send := func(client *http.Client, name string) *cassetteUser {
+		user := &cassetteUser{}
+		rsp := &DefaultResponse{Data: user}
+		req := Req().WithHostName(server.URL).WithPath("/users").
+			WithHeaders(map[string]string{"Authorization": "Bearer abc"}).
+			WithQueries(cassetteQuery{Page: 1, Token: "q-secret"}).
+			WithBody(cassetteUser{Name: name, Password: "p-secret"})
+		req.Client = client
+		req.Post(rsp)
+		if rsp.Error() != nil {
+			t.Fatalf("Post() error = %v", rsp.Error())
+		}
+		return user
+	}
+	for _, name := range []string{"alice", "bobby"} {
+		if user := send(recorder.Client(), name); user.Name != name || user.Token != "t0ken" {
+			t.Errorf("recorded response = %+v, want %s with token", user, name)
+		}
+	}
+	avatar := Req().WithHostName(server.URL).WithPath("/avatar")
+	avatar.Client = recorder.Client()
+	avatar.Get(&DefaultResponse{})
+	server.Close()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	for _, secret := range []string{"Bearer abc", "q-secret", "p-secret", "t0ken", "session=abc"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette file leaks %s:\n%s", secret, data)
+		}
+	}
+	if interactions := recorder.Interactions(); len(interactions) != 3 || interactions[2].Response.Encoding != bodyEncodingBase64 {
+		t.Fatalf("recorded interactions = %v, want 3 with base64 avatar", interactions)
+	}
+
+	tests := []struct {
+		name     string
+		config   CassetteConfig
+		names    []string
+		want     []string
+		wantMiss bool
+	}{
+		{
+			name:  "in recorded order",
+			names: []string{"alice", "alice"},
+			want:  []string{"alice", "bobby"},
+		},
+		{
+			name:     "used once",
+			names:    []string{"alice", "alice", "alice"},
+			want:     []string{"alice", "bobby"},
+			wantMiss: true,
+		},
+		{
+			name:   "replayable",
+			config: CassetteConfig{Replayable: true},
+			names:  []string{"alice", "alice", "alice"},
+			want:   []string{"alice", "bobby", "alice"},
+		},
+		{
+			name:   "match body",
+			config: CassetteConfig{Matchers: []CassetteMatcher{MatchMethod, MatchPath, MatchQuery, MatchBody}},
+			names:  []string{"bobby", "alice"},
+			want:   []string{"bobby", "alice"},
+		},
+		{
+			name:     "body not matched",
+			config:   CassetteConfig{Matchers: []CassetteMatcher{MatchBody}},
+			names:    []string{"carol"},
+			wantMiss: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.Redaction = redaction
+			player, err := NewCassette(path, config)
+			if err != nil {
+				t.Fatalf("NewCassette() error = %v", err)
+			}
+			var got []string
+			var missErr error
+			for _, name := range tt.names {
+				user := &cassetteUser{}
+				rsp := &DefaultResponse{Data: user}
+				req := Req().WithHostName("http://staging.example.com").WithPath("/users").
+					WithQueries(cassetteQuery{Page: 1, Token: "another"}).
+					WithBody(cassetteUser{Name: name, Password: "another"})
+				req.Client = player.Client()
+				req.Post(rsp)
+				if rsp.Error() != nil {
+					missErr = rsp.Error()
+					continue
+				}
+				got = append(got, user.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("replayed = %v, want %v", got, tt.want)
+			}
+			if (missErr != nil) != tt.wantMiss || (missErr != nil && !errors.Is(missErr, CassetteInteractionNotFoundError)) {
+				t.Errorf("replay error = %v, want miss %v", missErr, tt.wantMiss)
+			}
+		})
+	}
+
+	t.Run("service", func(t *testing.T) {
+		player, err := NewCassette(path, CassetteConfig{})
+		if err != nil {
+			t.Fatalf("NewCassette() error = %v", err)
+		}
+		service := &Service{Hosts: []string{"http://10.0.0.1:8080", "http://10.0.0.2:8080"}, Client: player.Client()}
+		rsp := &DefaultResponse{}
+		service.Serve().WithPath("/avatar").Get(rsp)
+		if rsp.Error() != nil || rsp.Raw == nil {
+			t.Fatalf("Get() error = %v", rsp.Error())
+		}
+		if rsp.Raw.StatusCode != http.StatusOK || rsp.Raw.ContentLength != 3 {
+			t.Errorf("replayed response = %d with %d bytes, want 200 with 3 bytes", rsp.Raw.StatusCode, rsp.Raw.ContentLength)
+		}
+	})
+}
+
+func TestNewCassette(t *testing.T) {
+	if _, err := NewCassette(filepath.Join(t.TempDir(), "missing.json"), CassetteConfig{}); err == nil {
+		t.Errorf("NewCassette() error = nil, want missing file")
+	}
+}
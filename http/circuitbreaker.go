@@ -0,0 +1,206 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var CircuitBreakerOpenError = errors.New("circuit breaker is open")
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+type CircuitBreakerConfig struct {
+	// ConsecutiveFailures opens the circuit when this many requests failed in a row. Zero disables the check.
+	ConsecutiveFailures int
+	// FailureRatio opens the circuit when the ratio of failed requests within Interval reaches it.
+	// Zero disables the check.
+	FailureRatio float64
+	// MinRequests is the least number of requests within Interval before FailureRatio is checked. Default is 10.
+	MinRequests int
+	// Interval is the cyclic period in closed state to clear the counts. Default is 1 minute.
+	Interval time.Duration
+	// CoolDown is how long the circuit keeps open before turning into half-open. Default is 30 seconds.
+	CoolDown time.Duration
+	// HalfOpenRequests is the number of trial requests allowed in half-open state,
+	// the circuit closes when all of them succeeded. Default is 1.
+	HalfOpenRequests int
+	// IsFailure reports whether the finished request should be counted as a failure.
+	// By default, 5xx responses and any other error except caller's cancellation and 4xx StatusError are failures.
+	IsFailure func(ctx *Context) bool
+}
+
+type circuitBreaker struct {
+	config      *CircuitBreakerConfig
+	mu          sync.Mutex
+	state       circuitState
+	generation  uint64
+	expiry      time.Time
+	requests    int
+	failures    int
+	consecutive int
+	inFlight    int
+	successes   int
+}
+
+// allow checks whether a request could pass, and returns the generation the request belongs to.
+func (cb *circuitBreaker) allow(now time.Time) (uint64, bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	switch cb.state {
+	case circuitOpen:
+		if now.Before(cb.expiry) {
+			return cb.generation, false
+		}
+		cb.toState(circuitHalfOpen, now)
+		fallthrough
+	case circuitHalfOpen:
+		if cb.inFlight >= cb.config.HalfOpenRequests {
+			return cb.generation, false
+		}
+		cb.inFlight++
+	default:
+		if !cb.expiry.IsZero() && !now.Before(cb.expiry) {
+			cb.toState(circuitClosed, now)
+		}
+	}
+	return cb.generation, true
+}
+
+// record counts the result of a request, results from a previous generation are ignored.
+func (cb *circuitBreaker) record(generation uint64, failed bool, now time.Time) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if generation != cb.generation {
+		return
+	}
+	switch cb.state {
+	case circuitHalfOpen:
+		cb.inFlight--
+		if failed {
+			cb.toState(circuitOpen, now)
+			return
+		}
+		cb.successes++
+		if cb.successes >= cb.config.HalfOpenRequests {
+			cb.toState(circuitClosed, now)
+		}
+	case circuitClosed:
+		cb.requests++
+		if !failed {
+			cb.consecutive = 0
+			return
+		}
+		cb.failures++
+		cb.consecutive++
+		if cb.shouldTrip() {
+			cb.toState(circuitOpen, now)
+		}
+	}
+}
+
+func (cb *circuitBreaker) shouldTrip() bool {
+	if cb.config.ConsecutiveFailures > 0 && cb.consecutive >= cb.config.ConsecutiveFailures {
+		return true
+	}
+	if cb.config.FailureRatio > 0 && cb.requests >= cb.config.MinRequests {
+		return float64(cb.failures)/float64(cb.requests) >= cb.config.FailureRatio
+	}
+	return false
+}
+
+// toState switches to the given state, starts a new generation and clears all the counts.
+func (cb *circuitBreaker) toState(state circuitState, now time.Time) {
+	cb.state = state
+	cb.generation++
+	cb.requests, cb.failures, cb.consecutive, cb.inFlight, cb.successes = 0, 0, 0, 0, 0
+	switch state {
+	case circuitOpen:
+		cb.expiry = now.Add(cb.config.CoolDown)
+	case circuitClosed:
+		cb.expiry = now.Add(cb.config.Interval)
+	default:
+		cb.expiry = time.Time{}
+	}
+}
+
+type circuitBreakers struct {
+	breakers map[interface{}]*circuitBreaker
+	config   *CircuitBreakerConfig
+	mu       sync.Mutex
+}
+
+func (c *circuitBreakers) getOrCreate(key interface{}) *circuitBreaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	breaker, ok := c.breakers[key]
+	if !ok {
+		breaker = &circuitBreaker{config: c.config, expiry: time.Now().Add(c.config.Interval)}
+		c.breakers[key] = breaker
+	}
+	return breaker
+}
+
+func defaultIsFailure(ctx *Context) bool {
+	if err := ctx.Response.Error(); err != nil {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			return statusErr.StatusCode >= http.StatusInternalServerError
+		}
+		return !errors.Is(err, context.Canceled)
+	}
+	rsp := ctx.Response.HttpResponse()
+	return rsp != nil && rsp.StatusCode >= http.StatusInternalServerError
+}
+
+// CircuitBreaker returns a middleware which tracks failures per bucket, and aborts requests with
+// CircuitBreakerOpenError while the circuit of the bucket is open.
+//
+// After CoolDown a few trial requests are let through (half-open), the circuit closes if all of them succeeded,
+// otherwise it opens again.
+func CircuitBreaker(config CircuitBreakerConfig, getter BucketGetter) HandlerFunc {
+	if config.MinRequests <= 0 {
+		config.MinRequests = 10
+	}
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	if config.CoolDown <= 0 {
+		config.CoolDown = 30 * time.Second
+	}
+	if config.HalfOpenRequests <= 0 {
+		config.HalfOpenRequests = 1
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = defaultIsFailure
+	}
+	breakers := circuitBreakers{
+		breakers: make(map[interface{}]*circuitBreaker),
+		config:   &config,
+	}
+
+	return func(ctx *Context) {
+		breaker := breakers.getOrCreate(getter(ctx))
+		generation, ok := breaker.allow(time.Now())
+		if !ok {
+			ctx.Abort()
+			ctx.Response.ErrorSave(CircuitBreakerOpenError)
+			return
+		}
+		// a panic in pending handlers counts as failure, so that half-open trials are always released
+		failed := true
+		defer func() {
+			breaker.record(generation, failed, time.Now())
+		}()
+		ctx.Next()
+		failed = config.IsFailure(ctx)
+	}
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	getter := func(ctx *Context) interface{} {
+		return 1
+	}
+	succeed := func(ctx *Context) {}
+	fail := func(ctx *Context) {
+		ctx.Response.ErrorSave(errors.New("connection refused"))
+	}
+	call := func(h HandlerFunc, downstream HandlerFunc) error {
+		ctx := &Context{Response: &DefaultResponse{}, handlers: []HandlerFunc{h, downstream}}
+		ctx.handlers[0](ctx)
+		return ctx.Response.Error()
+	}
+
+	tests := []struct {
+		name   string
+		config CircuitBreakerConfig
+		run    func(h HandlerFunc) bool
+	}{
+		{
+			name:   "consecutive failures open circuit",
+			config: CircuitBreakerConfig{ConsecutiveFailures: 3},
+			run: func(h HandlerFunc) bool {
+				for i := 0; i < 3; i++ {
+					if err := call(h, fail); errors.Is(err, CircuitBreakerOpenError) {
+						return false
+					}
+				}
+				return errors.Is(call(h, succeed), CircuitBreakerOpenError)
+			},
+		},
+		{
+			name:   "success resets consecutive failures",
+			config: CircuitBreakerConfig{ConsecutiveFailures: 3},
+			run: func(h HandlerFunc) bool {
+				for i := 0; i < 10; i++ {
+					_ = call(h, fail)
+					_ = call(h, fail)
+					if err := call(h, succeed); err != nil {
+						return false
+					}
+				}
+				return true
+			},
+		},
+		{
+			name:   "failure ratio opens circuit",
+			config: CircuitBreakerConfig{FailureRatio: 0.5, MinRequests: 4},
+			run: func(h HandlerFunc) bool {
+				_ = call(h, succeed)
+				_ = call(h, fail)
+				_ = call(h, succeed)
+				if err := call(h, fail); errors.Is(err, CircuitBreakerOpenError) {
+					return false
+				}
+				return errors.Is(call(h, succeed), CircuitBreakerOpenError)
+			},
+		},
+		{
+			name:   "half-open closes after success",
+			config: CircuitBreakerConfig{ConsecutiveFailures: 1, CoolDown: 10 * time.Millisecond},
+			run: func(h HandlerFunc) bool {
+				_ = call(h, fail)
+				if !errors.Is(call(h, succeed), CircuitBreakerOpenError) {
+					return false
+				}
+				time.Sleep(20 * time.Millisecond)
+				if err := call(h, succeed); err != nil {
+					return false
+				}
+				return call(h, succeed) == nil
+			},
+		},
+		{
+			name:   "half-open reopens after failure",
+			config: CircuitBreakerConfig{ConsecutiveFailures: 1, CoolDown: 10 * time.Millisecond},
+			run: func(h HandlerFunc) bool {
+				_ = call(h, fail)
+				time.Sleep(20 * time.Millisecond)
+				if err := call(h, fail); errors.Is(err, CircuitBreakerOpenError) {
+					return false
+				}
+				return errors.Is(call(h, succeed), CircuitBreakerOpenError)
+			},
+		},
+		{
+			name:   "half-open limits trial requests",
+			config: CircuitBreakerConfig{ConsecutiveFailures: 1, CoolDown: 10 * time.Millisecond},
+			run: func(h HandlerFunc) bool {
+				_ = call(h, fail)
+				time.Sleep(20 * time.Millisecond)
+				var nested error
+				_ = call(h, func(ctx *Context) {
+					nested = call(h, succeed)
+				})
+				return errors.Is(nested, CircuitBreakerOpenError)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CircuitBreaker(tt.config, getter)
+			if !tt.run(got) {
+				t.Error("CircuitBreaker() test failed")
+			}
+		})
+	}
+}
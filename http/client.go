@@ -0,0 +1,118 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultClient is the Client used by package-level functions like Use, Timeout, Headers and Req.
+var DefaultClient = NewClient()
+
+// Client owns the middlewares, headers, timeout and content resolvers shared by the Requests created from it.
+// Clients are isolated from each other, so libraries in one binary could keep their own configurations.
+type Client struct {
+	// HttpClient sends the HTTP requests, http.DefaultClient is used if it is nil.
+	// Note that Request.Client takes precedence over it.
+	HttpClient   *http.Client
+	mu           sync.RWMutex
+	handlers     []HandlerFunc
+	timeout      *time.Duration
+	compression  *Compression
+	headers      map[string]string
+	contentTypes map[string]ContentTypeResolver
+}
+
+// NewClient returns a new Client without any middleware, header or timeout.
+func NewClient() *Client {
+	return &Client{
+		headers:      make(map[string]string),
+		contentTypes: make(map[string]ContentTypeResolver),
+	}
+}
+
+// Use adds middlewares which will take effect in every Request created from current Client.
+func (c *Client) Use(middlewares ...HandlerFunc) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers = append(c.handlers, middlewares...)
+	return c
+}
+
+// Timeout sets timeout for each Request created from current Client.
+// If a middleware intercepts the Request, and the HTTP request never be fired, this timeout will take no effect.
+func (c *Client) Timeout(d time.Duration) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timeout = &d
+	return c
+}
+
+// Compression compresses request body with the given encoding for each Request created from current Client,
+// if the body size reaches threshold.
+func (c *Client) Compression(encoding string, threshold int) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.compression = &Compression{Encoding: encoding, Threshold: threshold}
+	return c
+}
+
+// Headers adds headers for each Request created from current Client.
+func (c *Client) Headers(headers map[string]string) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, v := range headers {
+		c.headers[k] = v
+	}
+	return c
+}
+
+// RegisterContentResolver registers specified ContentTypeResolver for the given contentType, which only takes effect
+// in Requests created from current Client. It takes precedence over the ones registered by RegisterContentResolver.
+func (c *Client) RegisterContentResolver(contentType string, resolver ContentTypeResolver) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.contentTypes[contentType] = resolver
+	return c
+}
+
+// contentResolver returns the ContentTypeResolver of Client for the given contentType, falling back to the
+// package-level registry.
+func (c *Client) contentResolver(contentType string) (ContentTypeResolver, bool) {
+	if c != nil {
+		c.mu.RLock()
+		resolver, ok := c.contentTypes[contentType]
+		c.mu.RUnlock()
+		if ok {
+			return resolver, true
+		}
+	}
+	resolver, ok := contentTypeRegistry[contentType]
+	return resolver, ok
+}
+
+// Req returns a new Request instance which inherits the configurations of current Client.
+// Configurations of the Request could be modified without affecting Client or other Requests.
+func (c *Client) Req() *Request {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ctx := &Context{Context: context.Background()}
+	req := &Request{
+		Client: c.HttpClient,
+		client: c,
+	}
+	if c.timeout != nil {
+		timeout := *c.timeout
+		req.Timeout = &timeout // timeout can be override later by calling WithTimeout() in Request
+	}
+	if c.compression != nil {
+		compression := *c.compression
+		req.Compression = &compression
+	}
+	req.WithHeaders(c.headers)
+	ctx.Request = req
+	req.ctx = ctx
+	ctx.handlers = append(ctx.handlers, c.handlers...)
+	return req
+}
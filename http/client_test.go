@@ -0,0 +1,102 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClientReq(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func() bool
+	}{
+		{
+			name: "request headers are isolated",
+			run: func() bool {
+				client := NewClient().Headers(map[string]string{"X-App": "a"})
+				first := client.Req().WithHeaders(map[string]string{"X-Trace": "1"})
+				second := client.Req()
+				return first.Headers["X-Trace"] == "1" &&
+					reflect.DeepEqual(second.Headers, map[string]string{"X-App": "a"}) &&
+					reflect.DeepEqual(client.headers, map[string]string{"X-App": "a"})
+			},
+		},
+		{
+			name: "clients are isolated",
+			run: func() bool {
+				a := NewClient().Headers(map[string]string{"X-App": "a"}).Timeout(time.Second).Use(Recovery())
+				b := NewClient()
+				req := b.Req()
+				return len(req.Headers) == 0 && req.Timeout == nil && len(req.ctx.handlers) == 0 &&
+					len(a.Req().ctx.handlers) == 1
+			},
+		},
+		{
+			name: "request timeout is isolated",
+			run: func() bool {
+				client := NewClient().Timeout(time.Second)
+				req := client.Req()
+				*req.Timeout = time.Minute
+				return *client.Req().Timeout == time.Second
+			},
+		},
+		{
+			name: "request middlewares are isolated",
+			run: func() bool {
+				client := NewClient().Use(Recovery(), Recovery())
+				client.Req().Use(Logger())
+				return len(client.Req().ctx.handlers) == 2
+			},
+		},
+		{
+			name: "content resolver",
+			run: func() bool {
+				client := NewClient().RegisterContentResolver("application/vnd.test", &contentTypeJson{})
+				_, ok := client.contentResolver("application/vnd.test")
+				_, okDefault := DefaultClient.contentResolver("application/vnd.test")
+				_, okJson := client.contentResolver(ContentTypeJson)
+				return ok && !okDefault && okJson
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.run() {
+				t.Error("Client.Req() test failed")
+			}
+		})
+	}
+}
+
+func TestClientMiddlewares(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"app": "` + r.Header.Get("X-App") + `"}`))
+	}))
+	defer server.Close()
+
+	var called []string
+	mark := func(name string) HandlerFunc {
+		return func(ctx *Context) {
+			called = append(called, name)
+			ctx.Next()
+		}
+	}
+	client := NewClient().Use(mark("client")).Headers(map[string]string{"X-App": "kits"})
+	client.HttpClient = server.Client()
+
+	data := map[string]string{}
+	rsp := &DefaultResponse{Data: &data}
+	client.Req().WithHostName(server.URL).Use(mark("request")).Get(rsp)
+	if rsp.Error() != nil {
+		t.Fatal(rsp.Error())
+	}
+	if !reflect.DeepEqual(called, []string{"client", "request"}) {
+		t.Errorf("Client.Use() called = %v", called)
+	}
+	if data["app"] != "kits" {
+		t.Errorf("Client.Headers() got = %v", data)
+	}
+}
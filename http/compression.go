@@ -0,0 +1,201 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	ContentEncodingHeader = "Content-Encoding"
+	EncodingGzip          = "gzip"
+	EncodingDeflate       = "deflate"
+	EncodingZstd          = "zstd"
+)
+
+var (
+	contentEncodingMu       sync.RWMutex
+	contentEncodingRegistry = map[string]ContentEncoder{
+		EncodingGzip:    gzipEncoder{},
+		EncodingDeflate: deflateEncoder{},
+		EncodingZstd:    zstdEncoder{},
+	}
+)
+
+// ContentEncoder compresses request bodies and decompresses response bodies of a Content-Encoding.
+type ContentEncoder interface {
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// RegisterContentEncoder registers specified ContentEncoder for the given encoding, ie: br.
+func RegisterContentEncoder(encoding string, encoder ContentEncoder) {
+	contentEncodingMu.Lock()
+	defer contentEncodingMu.Unlock()
+	contentEncodingRegistry[strings.ToLower(encoding)] = encoder
+}
+
+func contentEncoder(encoding string) (ContentEncoder, bool) {
+	contentEncodingMu.RLock()
+	defer contentEncodingMu.RUnlock()
+	encoder, ok := contentEncodingRegistry[strings.ToLower(strings.TrimSpace(encoding))]
+	return encoder, ok
+}
+
+// Compression compresses request bodies with Encoding once they are not smaller than Threshold bytes.
+// Bodies of unknown size, ie: a streamed multipart body or an io.Reader without Len method, are always compressed.
+// Bodies are never compressed if Content-Encoding header is set, that is, they are compressed by caller already.
+type Compression struct {
+	Encoding  string
+	Threshold int
+}
+
+// WithCompression compresses request body of current Request with the given encoding if its size reaches threshold.
+// Note that this will override global compression.
+func (r *Request) WithCompression(encoding string, threshold int) *Request {
+	r.Compression = &Compression{Encoding: encoding, Threshold: threshold}
+	return r
+}
+
+// compress returns the compressed body, the body is returned as it is if it should not be compressed.
+func (c *Compression) compress(body io.Reader) (io.Reader, bool, error) {
+	if c == nil || body == nil {
+		return body, false, nil
+	}
+	encoder, ok := contentEncoder(c.Encoding)
+	if !ok {
+		return nil, false, fmt.Errorf("unrecognized content encoding %s", c.Encoding)
+	}
+	sized, ok := body.(interface{ Len() int })
+	if !ok {
+		reader, writer := io.Pipe()
+		go func() {
+			err := encode(encoder, writer, body)
+			// the body would have been closed by http.Transport if it was not compressed
+			if closer, ok := body.(io.Closer); ok {
+				_ = closer.Close()
+			}
+			_ = writer.CloseWithError(err)
+		}()
+		return reader, true, nil
+	}
+	if sized.Len() < c.Threshold {
+		return body, false, nil
+	}
+	// body of known size is compressed in memory, so that Content-Length could be sent
+	var buf bytes.Buffer
+	if err := encode(encoder, &buf, body); err != nil {
+		return nil, false, err
+	}
+	return &buf, true, nil
+}
+
+func encode(encoder ContentEncoder, dst io.Writer, src io.Reader) error {
+	w, err := encoder.NewWriter(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(w, src); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// decodingBody decompresses response body by Content-Encoding lazily, so that empty bodies are fine.
+type decodingBody struct {
+	body     io.ReadCloser
+	encoders []ContentEncoder
+	reader   io.Reader
+	closers  []io.Closer
+	err      error
+}
+
+// newDecodingBody returns nil if there is no encoding but identity, and an error if any of the encodings is not
+// registered.
+func newDecodingBody(body io.ReadCloser, contentEncoding string) (*decodingBody, error) {
+	var encoders []ContentEncoder
+	for _, encoding := range strings.Split(contentEncoding, ",") {
+		encoding = strings.TrimSpace(encoding)
+		if encoding == "" || strings.EqualFold(encoding, "identity") {
+			continue
+		}
+		encoder, ok := contentEncoder(encoding)
+		if !ok {
+			return nil, fmt.Errorf("unrecognized content encoding %s", encoding)
+		}
+		encoders = append(encoders, encoder)
+	}
+	if len(encoders) == 0 {
+		return nil, nil
+	}
+	return &decodingBody{body: body, encoders: encoders}, nil
+}
+
+func (d *decodingBody) Read(p []byte) (int, error) {
+	if d.reader == nil && d.err == nil {
+		d.reader = d.body
+		// encodings are listed in the order they were applied
+		for i := len(d.encoders) - 1; i >= 0; i-- {
+			reader, err := d.encoders[i].NewReader(d.reader)
+			if err != nil {
+				d.err = err
+				break
+			}
+			d.closers = append(d.closers, reader)
+			d.reader = reader
+		}
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.reader.Read(p)
+}
+
+func (d *decodingBody) Close() error {
+	for _, closer := range d.closers {
+		_ = closer.Close()
+	}
+	return d.body.Close()
+}
+
+type gzipEncoder struct{}
+
+func (gzipEncoder) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipEncoder) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// deflateEncoder is the zlib format, which is what HTTP means by deflate.
+type deflateEncoder struct{}
+
+func (deflateEncoder) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zlib.NewWriter(w), nil
+}
+
+func (deflateEncoder) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return zlib.NewReader(r)
+}
+
+type zstdEncoder struct{}
+
+func (zstdEncoder) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+
+func (zstdEncoder) NewReader(r io.Reader) (io.ReadCloser, error) {
+	decoder, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return decoder.IOReadCloser(), nil
+}
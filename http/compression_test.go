@@ -0,0 +1,288 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// reverseEncoder stands for an encoder registered by user, ie: br.
+type reverseEncoder struct{}
+
+const encodingReverse = "x-reverse"
+
+type reverseWriter struct {
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func (r *reverseWriter) Write(p []byte) (int, error) {
+	return r.buf.Write(p)
+}
+
+func (r *reverseWriter) Close() error {
+	_, err := r.w.Write(reverse(r.buf.Bytes()))
+	return err
+}
+
+func reverse(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[len(b)-1-i] = b[i]
+	}
+	return out
+}
+
+func (reverseEncoder) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return &reverseWriter{w: w}, nil
+}
+
+func (reverseEncoder) NewReader(r io.Reader) (io.ReadCloser, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(reverse(b))), nil
+}
+
+func TestRequestWithCompression(t *testing.T) {
+	RegisterContentEncoder(encodingReverse, reverseEncoder{})
+	defer func() {
+		contentEncodingMu.Lock()
+		delete(contentEncodingRegistry, encodingReverse)
+		contentEncodingMu.Unlock()
+	}()
+
+	type echo struct {
+		Encoding string `json:"encoding"`
+		Length   int64  `json:"length"`
+		Body     string `json:"body"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		if decoding, _ := newDecodingBody(r.Body, r.Header.Get(ContentEncodingHeader)); decoding != nil {
+			body = decoding
+		}
+		raw, err := ioutil.ReadAll(body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set(ContentTypeHeader, ContentTypeJson)
+		_, _ = w.Write([]byte(toJson(echo{Encoding: r.Header.Get(ContentEncodingHeader), Length: r.ContentLength, Body: string(raw)})))
+	}))
+	defer server.Close()
+
+	large := strings.Repeat("a", 2048)
+	tests := []struct {
+		name         string
+		compression  *Compression
+		client       *Compression
+		headers      map[string]string
+		body         interface{}
+		wantEncoding string
+		wantBody     string
+		wantErr      bool
+	}{
+		{
+			name:     "disabled",
+			body:     large,
+			wantBody: `"` + large + `"`,
+		},
+		{
+			name:         "gzip above threshold",
+			compression:  &Compression{Encoding: EncodingGzip, Threshold: 1024},
+			body:         large,
+			wantEncoding: EncodingGzip,
+			wantBody:     `"` + large + `"`,
+		},
+		{
+			name:        "below threshold",
+			compression: &Compression{Encoding: EncodingGzip, Threshold: 1024},
+			body:        "small",
+			wantBody:    `"small"`,
+		},
+		{
+			name:         "deflate from client",
+			client:       &Compression{Encoding: EncodingDeflate},
+			body:         []byte(large),
+			wantEncoding: EncodingDeflate,
+			wantBody:     large,
+		},
+		{
+			name:         "zstd",
+			compression:  &Compression{Encoding: EncodingZstd},
+			body:         large,
+			wantEncoding: EncodingZstd,
+			wantBody:     `"` + large + `"`,
+		},
+		{
+			name:         "registered encoder",
+			compression:  &Compression{Encoding: encodingReverse},
+			body:         map[string]string{"k": "v"},
+			wantEncoding: encodingReverse,
+			wantBody:     `{"k":"v"}`,
+		},
+		{
+			name:         "reader of unknown size",
+			compression:  &Compression{Encoding: EncodingGzip, Threshold: 1 << 20},
+			body:         io.LimitReader(strings.NewReader(large), 100),
+			wantEncoding: EncodingGzip,
+			wantBody:     large[:100],
+		},
+		{
+			name:         "compressed by caller",
+			compression:  &Compression{Encoding: EncodingGzip},
+			headers:      map[string]string{ContentEncodingHeader: EncodingDeflate},
+			body:         deflate(large),
+			wantEncoding: EncodingDeflate,
+			wantBody:     large,
+		},
+		{
+			name:        "unknown encoding",
+			compression: &Compression{Encoding: "br"},
+			body:        large,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient()
+			if tt.client != nil {
+				client.Compression(tt.client.Encoding, tt.client.Threshold)
+			}
+			req := client.Req().WithHostName(server.URL).WithHeaders(tt.headers).WithBody(tt.body)
+			if tt.compression != nil {
+				req.WithCompression(tt.compression.Encoding, tt.compression.Threshold)
+			}
+			got := &echo{}
+			rsp := &DefaultResponse{Data: got}
+			req.Post(rsp)
+			if (rsp.Error() != nil) != tt.wantErr {
+				t.Fatalf("Post() error = %v, wantErr %v", rsp.Error(), tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Encoding != tt.wantEncoding || got.Body != tt.wantBody {
+				t.Errorf("Post() got encoding %q body %.20q, want %q %.20q", got.Encoding, got.Body, tt.wantEncoding, tt.wantBody)
+			}
+			if got.Encoding != "" && len(tt.wantBody) > 1024 && got.Length >= int64(len(tt.wantBody)) {
+				t.Errorf("Post() sent %d bytes, want compressed", got.Length)
+			}
+		})
+	}
+}
+
+// closeNotifier is a body of unknown size, which is compressed by a goroutine.
+type closeNotifier struct {
+	io.Reader
+	closed chan struct{}
+}
+
+func (c *closeNotifier) Close() error {
+	close(c.closed)
+	return nil
+}
+
+func TestRequestWithCompressionFailed(t *testing.T) {
+	body := &closeNotifier{Reader: strings.NewReader(strings.Repeat("a", 1<<20)), closed: make(chan struct{})}
+	req := Req().WithHostName("http://127.0.0.1:1").WithBody(body).WithCompression(EncodingGzip, 0)
+	if _, _, err := Do[struct{}](req, "BAD METHOD"); err == nil {
+		t.Fatalf("Do() error = nil, want invalid method")
+	}
+	select {
+	case <-body.closed:
+	case <-time.After(time.Second):
+		t.Errorf("Do() never closed the body, the compressing goroutine is blocked")
+	}
+}
+
+func TestResponseDecompression(t *testing.T) {
+	const payload = `{"name":"compressed"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ContentTypeHeader, ContentTypeJson)
+		encoding := r.URL.Query().Get("encoding")
+		body := []byte(payload)
+		switch encoding {
+		case "gzip":
+			body = gzipped(payload)
+		case "deflate, gzip":
+			body = gzipped(string(deflate(payload)))
+		case "zstd":
+			encoder, _ := zstd.NewWriter(nil)
+			body = encoder.EncodeAll(body, nil)
+		case "br":
+			body = []byte("opaque")
+		}
+		if encoding != "" {
+			w.Header().Set(ContentEncodingHeader, encoding)
+		}
+		if r.Method == http.MethodHead {
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		encoding string
+		want     string
+		wantErr  bool
+	}{
+		{name: "identity", want: "compressed"},
+		{name: "gzip", encoding: "gzip", want: "compressed"},
+		{name: "stacked encodings", encoding: "deflate, gzip", want: "compressed"},
+		{name: "zstd", encoding: "zstd", want: "compressed"},
+		{name: "unknown encoding", encoding: "br", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient()
+			client.HttpClient = &http.Client{Transport: &http.Transport{DisableCompression: true}}
+			got := &struct {
+				Name string `json:"name"`
+			}{}
+			rsp := &DefaultResponse{Data: got}
+			client.Req().WithHostName(server.URL).WithQueries(map[string]string{"encoding": tt.encoding}).Get(rsp)
+			if (rsp.Error() != nil) != tt.wantErr {
+				t.Fatalf("Get() error = %v, wantErr %v", rsp.Error(), tt.wantErr)
+			}
+			if tt.wantErr && rsp.Error().Error() != "unrecognized content encoding "+tt.encoding {
+				t.Errorf("Get() error = %v, want unrecognized content encoding", rsp.Error())
+			}
+			if got.Name != tt.want {
+				t.Errorf("Get() got = %q, want %q", got.Name, tt.want)
+			}
+			if header := rsp.HttpResponse().Header.Get(ContentEncodingHeader); !tt.wantErr && header != "" {
+				t.Errorf("Get() Content-Encoding = %q, want removed", header)
+			}
+		})
+	}
+}
+
+func gzipped(s string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, _ = w.Write([]byte(s))
+	_ = w.Close()
+	return buf.Bytes()
+}
+
+func deflate(s string) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	_, _ = w.Write([]byte(s))
+	_ = w.Close()
+	return buf.Bytes()
+}
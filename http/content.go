@@ -2,8 +2,11 @@ package http
 
 import (
 	"encoding/json"
-	"errors"
+	"encoding/xml"
 	"fmt"
+	"log/slog"
+	"mime"
+	"strings"
 )
 
 var contentTypeRegistry map[string]ContentTypeResolver
@@ -12,6 +15,12 @@ func init() {
 	contentTypeRegistry = make(map[string]ContentTypeResolver)
 	contentTypeRegistry[ContentTypeJson] = &contentTypeJson{}
 	contentTypeRegistry[ContentTypeFrom] = &contentTypeForm{}
+	contentTypeRegistry[ContentTypeProblemJson] = &contentTypeJson{}
+	contentTypeRegistry[ContentTypeXml] = &contentTypeXml{}
+	contentTypeRegistry[ContentTypeTextXml] = &contentTypeXml{}
+	contentTypeRegistry[ContentTypeMultipart] = &contentTypeMultipart{}
+	contentTypeRegistry[ContentTypeEventStream] = &contentTypeEventStream{}
+	contentTypeRegistry[ContentTypeNDJson] = &contentTypeNDJson{}
 }
 
 type ContentTypeResolver interface {
@@ -22,18 +31,39 @@ type ContentTypeResolver interface {
 // RegisterContentResolver register specified ContentTypeResolver for the given contentType
 func RegisterContentResolver(contentType string, resolver ContentTypeResolver) {
 	if _, ok := contentTypeRegistry[contentType]; ok {
-		_, _ = fmt.Fprintf(DefaultWriter, "trying to override ContentTypeResolver for ContentType %s to %v", contentType, resolver)
+		DefaultLogger.Warn("trying to override ContentTypeResolver",
+			slog.String("content_type", contentType), slog.String("resolver", fmt.Sprintf("%T", resolver)))
 	}
 	contentTypeRegistry[contentType] = resolver
 }
 
 const (
-	ContentTypeHeader = "Content-Type"
-	AcceptTypeHeader  = "Accept"
-	ContentTypeJson   = "application/json"
-	ContentTypeFrom   = "application/x-www-form-urlencoded"
+	ContentTypeHeader  = "Content-Type"
+	AcceptTypeHeader   = "Accept"
+	ContentTypeJson    = "application/json"
+	ContentTypeFrom    = "application/x-www-form-urlencoded"
+	ContentTypeXml     = "application/xml"
+	ContentTypeTextXml = "text/xml"
 )
 
+// mediaType strips the parameters of content type, ie: charset.
+func mediaType(contentType string) string {
+	if t, _, err := mime.ParseMediaType(contentType); err == nil {
+		return t
+	}
+	return strings.TrimSpace(strings.ToLower(contentType))
+}
+
+// acceptResolver returns the resolver for the first media type in Accept header which could be resolved by client.
+func acceptResolver(client *Client, accept string) (ContentTypeResolver, bool) {
+	for _, t := range strings.Split(accept, ",") {
+		if resolver, ok := client.contentResolver(mediaType(t)); ok {
+			return resolver, true
+		}
+	}
+	return nil, false
+}
+
 type contentTypeJson struct{}
 
 func (c *contentTypeJson) Marshal(v interface{}) ([]byte, error) {
@@ -56,5 +86,15 @@ func (c *contentTypeForm) Marshal(v interface{}) ([]byte, error) {
 }
 
 func (c *contentTypeForm) Unmarshal(bytes []byte, v interface{}) error {
-	return errors.New("implement me")
+	return decodeForm(bytes, v, formTagName)
+}
+
+type contentTypeXml struct{}
+
+func (c *contentTypeXml) Marshal(v interface{}) ([]byte, error) {
+	return xml.Marshal(v)
+}
+
+func (c *contentTypeXml) Unmarshal(data []byte, v interface{}) error {
+	return xml.Unmarshal(data, v)
 }
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestXmlContentType(t *testing.T) {
+	type order struct {
+		XMLName xml.Name `xml:"order"`
+		ID      string   `xml:"id,attr"`
+		Amount  int      `xml:"amount"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set(ContentTypeHeader, r.Header.Get(ContentTypeHeader))
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		contentType string
+		accept      string
+	}{
+		{name: "application/xml", contentType: ContentTypeXml, accept: ContentTypeXml},
+		{name: "text/xml", contentType: ContentTypeTextXml, accept: ContentTypeTextXml},
+		{name: "charset", contentType: ContentTypeXml + "; charset=utf-8", accept: "text/html, text/xml;q=0.9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := order{XMLName: xml.Name{Local: "order"}, ID: "o-1", Amount: 100}
+			got := order{}
+			rsp := &DefaultResponse{Data: &got}
+			Req().WithHostName(server.URL).ContentType(tt.contentType).Accept(tt.accept).WithBody(want).Post(rsp)
+			if rsp.Error() != nil {
+				t.Fatal(rsp.Error())
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Request.Post() got = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestRequestContentType(t *testing.T) {
+	client := NewClient().RegisterContentResolver("application/vnd.kits+json", &contentTypeJson{})
+	tests := []struct {
+		name        string
+		req         *Request
+		contentType string
+		wantErr     bool
+	}{
+		{name: "json", req: Req(), contentType: ContentTypeJson},
+		{name: "form", req: Req(), contentType: ContentTypeFrom},
+		{name: "xml", req: Req(), contentType: ContentTypeXml},
+		{name: "with parameters", req: Req(), contentType: "application/json; charset=utf-8"},
+		{name: "client resolver", req: client.Req(), contentType: "application/vnd.kits+json"},
+		{name: "unregistered", req: Req(), contentType: "application/vnd.kits+json", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req.ContentType(tt.contentType).Accept(tt.contentType)
+			if (req.Error() != nil) != tt.wantErr {
+				t.Errorf("Request.ContentType() error = %v, wantErr %v", req.Error(), tt.wantErr)
+				return
+			}
+			if !tt.wantErr && (req.Headers[ContentTypeHeader] != tt.contentType || req.Headers[AcceptTypeHeader] != tt.contentType) {
+				t.Errorf("Request.ContentType() headers = %v", req.Headers)
+			}
+		})
+	}
+}
@@ -8,29 +8,20 @@ import (
 
 type HandlerFunc func(*Context)
 
-var globalHandlers []HandlerFunc
-var globalTimeout *time.Duration
-var globalHeaders map[string]string
-
-// Use adds global middlewares which will take effect in every Request
+// Use adds global middlewares which will take effect in every Request created by Req.
 func Use(middlewares ...HandlerFunc) {
-	globalHandlers = append(globalHandlers, middlewares...)
+	DefaultClient.Use(middlewares...)
 }
 
-// Timeout set global timeout for each Request.
+// Timeout set global timeout for each Request created by Req.
 // If a middleware intercepts the Request, and the HTTP request never be fired, this timeout will take no effect.
 func Timeout(d time.Duration) {
-	globalTimeout = &d
+	DefaultClient.Timeout(d)
 }
 
-// Headers add global headers for each Request.
+// Headers add global headers for each Request created by Req.
 func Headers(headers map[string]string) {
-	if globalHeaders == nil {
-		globalHeaders = make(map[string]string)
-	}
-	for k, v := range headers {
-		globalHeaders[k] = v
-	}
+	DefaultClient.Headers(headers)
 }
 
 type Context struct {
@@ -39,7 +30,18 @@ type Context struct {
 	Method   string
 	handlers []HandlerFunc
 	index    int
+	attempt  int
 	params   map[string]interface{}
+	// responseSize is the size of response body read so far
+	responseSize int64
+	// captureBody is the max bytes of request and response bodies captured for logs, zero disables capturing
+	captureBody  int
+	requestBody  *captureBuffer
+	responseBody *captureBuffer
+	// trace collects the Timings of the last attempt
+	trace *clientTrace
+	// cancel releases the timeout context of Request, it is handed to streamed response body if there is one
+	cancel context.CancelFunc
 	context.Context
 }
 
@@ -52,6 +54,15 @@ func (c *Context) Next() {
 	}
 }
 
+// Attempt returns the number of the current attempt, starting from 1.
+// It only grows when a Retry middleware re-runs the pending handlers.
+func (c *Context) Attempt() int {
+	if c.attempt == 0 {
+		return 1
+	}
+	return c.attempt
+}
+
 // Abort prevents pending handlers from being called. Note that this will not stop the current handler.
 func (c *Context) Abort() {
 	// When the last handler has been called, c.index = len(c.handlers).
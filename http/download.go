@@ -0,0 +1,261 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	partSuffix = ".part"
+	metaSuffix = ".part.meta"
+)
+
+var ChecksumMismatchError = errors.New("checksum mismatch")
+
+type DownloadOptions struct {
+	// SHA256 is the expected hex encoded SHA-256 of the file, it is not verified if empty.
+	// The partial file is removed if the checksum mismatches, so the next Download starts from zero.
+	SHA256 string
+	// Progress is called whenever data is written, total is -1 if the size is unknown.
+	Progress func(written, total int64)
+	// MaxResumes is the max times an interrupted transfer is resumed within one Download. Default is 3.
+	MaxResumes int
+	// Backoff decides how long to wait before resuming. Default is ExponentialBackoff(100ms, 10s).
+	Backoff BackoffFunc
+}
+
+// downloadMeta is saved beside the partial file, it tells whether the partial file could be resumed.
+type downloadMeta struct {
+	// Validator is the strong ETag or Last-Modified of the response that the partial file comes from.
+	Validator string `json:"validator"`
+}
+
+// Download fetches current Request with HTTP Get into dstPath through the handler chain.
+// The data is written into dstPath.part, which is renamed to dstPath once the transfer completes and the checksum
+// matches. An interrupted transfer, in the same Download or a later one, is resumed from the end of the partial file
+// with Range and If-Range, so that it restarts from zero if the file has changed on server. Only lost connections
+// are resumed, the other errors, ie: error status, validation errors and local file errors, are returned at once.
+//
+// Note that Timeout applies to every connection, including reading its body.
+func (r *Request) Download(ctx context.Context, dstPath string, opts DownloadOptions) error {
+	if r.err != nil {
+		return r.err
+	}
+	maxResumes := opts.MaxResumes
+	if maxResumes <= 0 {
+		maxResumes = 3
+	}
+	backoff := opts.Backoff
+	if backoff == nil {
+		backoff = ExponentialBackoff(100*time.Millisecond, 10*time.Second)
+	}
+	r.WithContext(ctx)
+	partPath, metaPath := dstPath+partSuffix, dstPath+metaSuffix
+
+	for resumes := 0; ; resumes++ {
+		err := r.downloadPart(partPath, metaPath, opts.Progress)
+		if err == nil {
+			break
+		}
+		// errors of the local files wrap syscall.Errno, which is a net.Error as well
+		var pathErr *os.PathError
+		if resumes >= maxResumes || errors.As(err, &pathErr) || !r.reconnectable(err) {
+			return err
+		}
+		timer := time.NewTimer(backoff(resumes + 1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+
+	if opts.SHA256 != "" {
+		if err := verifySHA256(partPath, opts.SHA256); err != nil {
+			_ = os.Remove(partPath)
+			_ = os.Remove(metaPath)
+			return err
+		}
+	}
+	if err := os.Rename(partPath, dstPath); err != nil {
+		return err
+	}
+	_ = os.Remove(metaPath)
+	return nil
+}
+
+// downloadPart sends one request and appends its body to the partial file.
+func (r *Request) downloadPart(partPath, metaPath string, progress func(written, total int64)) error {
+	offset, validator := resumePoint(partPath, metaPath)
+	if r.Headers == nil {
+		r.Headers = make(map[string]string)
+	}
+	delete(r.Headers, "Range")
+	delete(r.Headers, "If-Range")
+	if offset > 0 {
+		r.Headers["Range"] = fmt.Sprintf("bytes=%d-", offset)
+		r.Headers["If-Range"] = validator
+	}
+
+	rsp := &StreamResponse{}
+	r.ctx.Method = http.MethodGet
+	r.do(rsp)
+	defer func() {
+		_ = rsp.Close()
+	}()
+	if err := rsp.Error(); err != nil {
+		var statusErr *StatusError
+		if offset > 0 && errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			if _, _, total := contentRange(statusErr.Header.Get("Content-Range")); total == offset {
+				// the previous transfer was interrupted right after the last byte
+				return nil
+			}
+			// the partial file does not fit the file on server any more
+			_ = os.Remove(partPath)
+			_ = os.Remove(metaPath)
+		}
+		return err
+	}
+
+	raw := rsp.HttpResponse()
+	flag, total := os.O_CREATE|os.O_WRONLY|os.O_TRUNC, raw.ContentLength
+	switch raw.StatusCode {
+	case http.StatusPartialContent:
+		start, _, size := contentRange(raw.Header.Get("Content-Range"))
+		if start != offset {
+			_ = os.Remove(partPath)
+			_ = os.Remove(metaPath)
+			return fmt.Errorf("unexpected Content-Range %s, want start from %d", raw.Header.Get("Content-Range"), offset)
+		}
+		flag, total = os.O_WRONLY|os.O_APPEND, size
+	default:
+		// the server ignored Range or the file has changed, start over
+		offset = 0
+	}
+	if err := saveDownloadMeta(metaPath, raw.Header); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(partPath, flag, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	writer := io.Writer(f)
+	if progress != nil {
+		writer = &progressWriter{w: f, written: offset, total: total, progress: progress}
+	}
+	if _, err = io.Copy(writer, rsp.Body); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// resumePoint returns the size of the partial file and the validator it could be resumed with.
+// Zero is returned if the partial file could not be resumed.
+func resumePoint(partPath, metaPath string) (int64, string) {
+	info, err := os.Stat(partPath)
+	if err != nil || info.Size() == 0 {
+		return 0, ""
+	}
+	b, err := ioutil.ReadFile(metaPath)
+	if err != nil {
+		return 0, ""
+	}
+	var meta downloadMeta
+	if err = json.Unmarshal(b, &meta); err != nil || meta.Validator == "" {
+		return 0, ""
+	}
+	return info.Size(), meta.Validator
+}
+
+// saveDownloadMeta saves the validator of response, If-Range only accepts strong ETag, so Last-Modified is used
+// if the ETag is weak. The meta file is removed if there is no validator, the partial file could not be resumed then.
+func saveDownloadMeta(metaPath string, header http.Header) error {
+	validator := header.Get("ETag")
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = header.Get("Last-Modified")
+	}
+	if validator == "" {
+		if err := os.Remove(metaPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	b, err := json.Marshal(&downloadMeta{Validator: validator})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(metaPath, b, 0644)
+}
+
+// contentRange parses Content-Range in form of `bytes start-end/total` or `bytes */total`,
+// -1 is returned for the unknown parts.
+func contentRange(value string) (start, end, total int64) {
+	start, end, total = -1, -1, -1
+	value = strings.TrimPrefix(value, "bytes ")
+	i := strings.IndexByte(value, '/')
+	if i < 0 {
+		return
+	}
+	if n, err := strconv.ParseInt(value[i+1:], 10, 64); err == nil {
+		total = n
+	}
+	if j := strings.IndexByte(value[:i], '-'); j >= 0 {
+		if n, err := strconv.ParseInt(value[:j], 10, 64); err == nil {
+			start = n
+		}
+		if n, err := strconv.ParseInt(value[j+1:i], 10, 64); err == nil {
+			end = n
+		}
+	}
+	return
+}
+
+func verifySHA256(path, want string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, f); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(got, want) {
+		return fmt.Errorf("%w: sha256 of %s is %s, want %s", ChecksumMismatchError, path, got, want)
+	}
+	return nil
+}
+
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	total    int64
+	progress func(written, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	if n > 0 {
+		p.progress(p.written, p.total)
+	}
+	return n, err
+}
@@ -0,0 +1,256 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRequestDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 64<<10)
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+	const etag = `"v2"`
+
+	var mu sync.Mutex
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		first := len(ranges) == 1
+		mu.Unlock()
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		if r.URL.Path == "/flaky" && first {
+			// the connection is lost in the middle of the body
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			_, _ = w.Write(content[:len(content)/3])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		path       string
+		partial    []byte
+		validator  string
+		sha256     string
+		wantRanges []string
+		wantErr    error
+		wantStatus int
+	}{
+		{
+			name:       "complete",
+			sha256:     checksum,
+			wantRanges: []string{""},
+		},
+		{
+			name:       "resume after interruption",
+			path:       "flaky",
+			sha256:     checksum,
+			wantRanges: []string{"", "bytes=" + strconv.Itoa(len(content)/3) + "-"},
+		},
+		{
+			name:       "resume previous partial file",
+			partial:    content[:1000],
+			validator:  etag,
+			wantRanges: []string{"bytes=1000-"},
+		},
+		{
+			name:       "partial file changed on server",
+			partial:    []byte("stale"),
+			validator:  `"v1"`,
+			sha256:     checksum,
+			wantRanges: []string{"bytes=5-"},
+		},
+		{
+			name:       "partial file without validator",
+			partial:    []byte("stale"),
+			wantRanges: []string{""},
+		},
+		{
+			name:       "partial file already complete",
+			partial:    content,
+			validator:  etag,
+			wantRanges: []string{"bytes=" + strconv.Itoa(len(content)) + "-"},
+		},
+		{
+			name:       "checksum mismatch",
+			sha256:     strings.Repeat("0", 64),
+			wantRanges: []string{""},
+			wantErr:    ChecksumMismatchError,
+		},
+		{
+			name:       "error status",
+			path:       "missing",
+			wantRanges: []string{""},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranges = nil
+			dst := filepath.Join(t.TempDir(), "data.bin")
+			if tt.partial != nil {
+				if err := ioutil.WriteFile(dst+partSuffix, tt.partial, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.validator != "" {
+				if err := ioutil.WriteFile(dst+metaSuffix, []byte(toJson(downloadMeta{Validator: tt.validator})), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var written, total int64
+			err := Req().WithHostName(server.URL).WithPath(tt.path).WithTimeout(5*time.Second).
+				Download(context.Background(), dst, DownloadOptions{
+					SHA256: tt.sha256,
+					Progress: func(w, t int64) {
+						written, total = w, t
+					},
+				})
+			if strings.Join(ranges, ",") != strings.Join(tt.wantRanges, ",") {
+				t.Errorf("Download() ranges = %q, want %q", ranges, tt.wantRanges)
+			}
+			var statusErr *StatusError
+			switch {
+			case tt.wantStatus != 0:
+				if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.wantStatus {
+					t.Errorf("Download() error = %v, want status %d", err, tt.wantStatus)
+				}
+				return
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Download() error = %v, want %v", err, tt.wantErr)
+				}
+				if _, statErr := os.Stat(dst + partSuffix); !os.IsNotExist(statErr) {
+					t.Errorf("Download() partial file is kept after %v", err)
+				}
+				return
+			case err != nil:
+				t.Fatalf("Download() error = %v", err)
+			}
+			got, err := ioutil.ReadFile(dst)
+			if err != nil || !bytes.Equal(got, content) {
+				t.Errorf("Download() got %d bytes, want %d, error %v", len(got), len(content), err)
+			}
+			for _, leftover := range []string{dst + partSuffix, dst + metaSuffix} {
+				if _, statErr := os.Stat(leftover); !os.IsNotExist(statErr) {
+					t.Errorf("Download() %s is left", leftover)
+				}
+			}
+			if written > 0 && (written != int64(len(content)) || total != int64(len(content))) {
+				t.Errorf("Download() progress = %d/%d, want %d", written, total, len(content))
+			}
+		})
+	}
+}
+
+func TestRequestDownloadResume(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.URL.Path == "/broken" {
+			// the connection is always lost in the middle of the body
+			w.Header().Set("Content-Length", "100")
+			_, _ = w.Write([]byte("partial"))
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		_, _ = w.Write([]byte("data"))
+	}))
+	defer server.Close()
+
+	const wait = 20 * time.Millisecond
+	tests := []struct {
+		name     string
+		path     string
+		dir      string
+		build    func(req *Request) *Request
+		wantRuns int32
+		wantErr  func(err error) bool
+	}{
+		{
+			name:     "wait between resumes",
+			path:     "broken",
+			wantRuns: 3,
+			wantErr: func(err error) bool {
+				return errors.Is(err, io.ErrUnexpectedEOF)
+			},
+		},
+		{
+			name: "validation error",
+			build: func(req *Request) *Request {
+				return req.WithQueries(struct {
+					Name string `query:"name,required"`
+				}{})
+			},
+			wantErr: func(err error) bool {
+				var validationErrs ValidationErrors
+				return errors.As(err, &validationErrs)
+			},
+		},
+		{
+			name: "middleware error",
+			build: func(req *Request) *Request {
+				return req.Use(func(ctx *Context) {
+					ctx.Response.ErrorSave(CircuitBreakerOpenError)
+					ctx.Abort()
+				})
+			},
+			wantRuns: 1,
+			wantErr: func(err error) bool {
+				return errors.Is(err, CircuitBreakerOpenError)
+			},
+		},
+		{
+			name:     "local file error",
+			dir:      "missing",
+			wantRuns: 1,
+			wantErr: func(err error) bool {
+				return os.IsNotExist(err)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var runs int32
+			req := Req().WithHostName(server.URL).WithPath(tt.path).Use(func(ctx *Context) {
+				runs++
+				ctx.Next()
+			})
+			if tt.build != nil {
+				req = tt.build(req)
+			}
+			start := time.Now()
+			err := req.Download(context.Background(), filepath.Join(t.TempDir(), tt.dir, "data.bin"), DownloadOptions{
+				MaxResumes: 2,
+				Backoff:    ConstantBackoff(wait),
+			})
+			if !tt.wantErr(err) || runs != tt.wantRuns {
+				t.Errorf("Download() error = %v after %d runs, want %d runs", err, runs, tt.wantRuns)
+			}
+			if elapsed, want := time.Since(start), time.Duration(tt.wantRuns-1)*wait; tt.wantRuns > 1 && elapsed < want {
+				t.Errorf("Download() took %v, want waiting at least %v", elapsed, want)
+			}
+		})
+	}
+}
@@ -1,10 +1,13 @@
 package http
 
 import (
+	"encoding"
 	"fmt"
 	"net/url"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -12,6 +15,10 @@ const (
 	attrRequired  = "required"
 	attrDefault   = "default"
 	attrEnum      = "enum"
+	attrExplode   = "explode"
+	attrBrackets  = "brackets"
+	attrLayout    = "layout"
+	attrPattern   = "pattern"
 
 	queryTagName = "query"
 	formTagName  = "form"
@@ -23,11 +30,31 @@ func encodeForm(m map[string]interface{}) string {
 	}
 	query := url.Values{}
 	for key, value := range m {
-		query.Add(key, fmt.Sprintf("%v", value))
+		query[key] = formValues(value)
 	}
+	// url.Values encodes keys in sorted order, and repeated values in their original order
 	return query.Encode()
 }
 
+// formValues returns the values of a key, slices other than []byte are repeated keys.
+func formValues(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	}
+	val := reflect.ValueOf(value)
+	if kind := val.Kind(); (kind == reflect.Slice || kind == reflect.Array) && val.Type().Elem().Kind() != reflect.Uint8 {
+		vs := make([]string, val.Len())
+		for i := range vs {
+			vs[i] = formatFormValue(val.Index(i), "")
+		}
+		return vs
+	}
+	return []string{fmt.Sprintf("%v", value)}
+}
+
 func formToMap(v interface{}, tagName string) (map[string]interface{}, error) {
 	typ := reflect.TypeOf(v)
 	isPtr := false
@@ -63,6 +90,74 @@ func reflectFormFromMap(query interface{}, isPtr bool) (map[string]interface{},
 	return m, nil
 }
 
+// formTag is the parsed tag of a struct field, ie: `query:"name,omitempty,explode,layout=2006-01-02"`.
+type formTag struct {
+	key string
+	// attrs reports whether there is any attribute other than validation rules, zero fields with attributes are
+	// omitted unless they have default.
+	attrs      bool
+	omitEmpty  bool
+	required   bool
+	enum       bool
+	explode    bool
+	brackets   bool
+	defaultVal *string
+	layout     string
+	// rules are the validation rules in the order of the tag, see Validate.
+	rules []validateRule
+}
+
+func parseFormTag(structField reflect.StructField, tagName string) formTag {
+	labelElems := strings.Split(structField.Tag.Get(tagName), ",")
+	tag := formTag{key: labelElems[0]}
+	if tag.key == "" {
+		tag.key = strings.ToLower(structField.Name)
+	}
+	for i := 1; i < len(labelElems); i++ {
+		attr := labelElems[i]
+		if strings.HasPrefix(attr, attrPattern+"=") {
+			// pattern takes the rest of the tag, as the regex may contain commas
+			attr, i = strings.Join(labelElems[i:], ","), len(labelElems)
+		}
+		name, param, _ := strings.Cut(attr, "=")
+		if validationRules[name] {
+			tag.rules = append(tag.rules, validateRule{name: name, param: param})
+			continue
+		}
+		tag.attrs = true
+		switch {
+		case attr == attrOmitEmpty:
+			tag.omitEmpty = true
+			tag.rules = append(tag.rules, validateRule{name: attrOmitEmpty})
+		case attr == attrRequired:
+			tag.required = true
+			tag.rules = append(tag.rules, validateRule{name: attrRequired})
+		case attr == attrEnum:
+			tag.enum = true
+		case attr == attrExplode:
+			tag.explode = true
+		case attr == attrBrackets:
+			tag.brackets = true
+		case strings.HasPrefix(attr, attrDefault+"="):
+			d := strings.TrimPrefix(attr, attrDefault+"=")
+			tag.defaultVal = &d
+		case strings.HasPrefix(attr, attrLayout+"="):
+			tag.layout = strings.TrimPrefix(attr, attrLayout+"=")
+		case attr != "":
+			// unknown attributes are reported by Validate
+			tag.rules = append(tag.rules, validateRule{name: name, param: param})
+		}
+	}
+	return tag
+}
+
+// reflectFormFromStruct flattens struct into form keys:
+//
+//   - embedded structs without tag are flattened into their parent
+//   - nested structs and maps are keyed by `parent.child`, or `parent[child]` with `brackets` attribute
+//   - time.Time is formatted by `layout` attribute, RFC 3339 by default, and encoding.TextMarshaler by MarshalText
+//   - nil pointers are omitted, unless they are required or have default, pointers to zero values are sent
+//   - slices are joined by comma, or pipe with `enum` attribute, and are repeated keys with `explode` attribute
 func reflectFormFromStruct(query interface{}, isPtr bool, tagName string) (map[string]interface{}, error) {
 	var val reflect.Value
 	if isPtr {
@@ -71,54 +166,157 @@ func reflectFormFromStruct(query interface{}, isPtr bool, tagName string) (map[s
 		val = reflect.ValueOf(query)
 	}
 	var m = make(map[string]interface{})
+	if err := structToForm(m, val, tagName, "", false); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func structToForm(m map[string]interface{}, val reflect.Value, tagName string, prefix string, brackets bool) error {
 	for i := 0; i < val.NumField(); i++ {
 		field := val.Field(i)
 		if !field.CanInterface() {
 			continue
 		}
 		structField := val.Type().Field(i)
-		tag := structField.Tag
-		label := tag.Get(tagName)
-		labelElems := strings.Split(label, ",")
-		key := labelElems[0]
-		if key == "" {
-			key = strings.ToLower(structField.Name)
-		}
-		tagAttrs := func(attrs []string) map[string]struct{} {
-			var set = make(map[string]struct{})
-			for j := range attrs {
-				set[attrs[j]] = struct{}{}
+		if structField.Tag.Get(tagName) == "-" {
+			continue
+		}
+		tag := parseFormTag(structField, tagName)
+
+		isNil := false
+		for field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				isNil = true
+				break
 			}
-			return set
-		}(labelElems[1:])
-		var enum bool
-		if len(labelElems) > 1 {
-			isZero := field.IsZero()
-			if _, omitempty := tagAttrs[attrOmitEmpty]; omitempty {
-				if isZero {
-					continue
-				}
+			field = field.Elem()
+		}
+		if structField.Anonymous && structField.Tag.Get(tagName) == "" && field.Kind() == reflect.Struct && !isFormScalar(field) {
+			if isNil {
+				continue
 			}
-			if _, required := tagAttrs[attrRequired]; required {
-				if isZero {
-					return nil, fmt.Errorf("required field `%s` is empty", structField.Name)
-				}
+			if err := structToForm(m, field, tagName, prefix, brackets); err != nil {
+				return err
 			}
-			if isZero {
-				for _, attr := range labelElems[1:] {
-					if strings.HasPrefix(attr, attrDefault) {
-						defaultVal := strings.TrimPrefix(attr, attrDefault)
-						m[key] = defaultVal[1:]
-						break
-					}
-				}
+			continue
+		}
+
+		key := nestedFormKey(prefix, tag.key, brackets)
+		// only nil pointers are empty, pointers are how zero values are sent explicitly
+		if isNil || val.Field(i).Kind() != reflect.Ptr && field.IsZero() {
+			if tag.omitEmpty {
+				continue
+			}
+			if tag.required {
+				return fmt.Errorf("required field `%s` is empty", structField.Name)
+			}
+			if tag.defaultVal != nil {
+				m[key] = *tag.defaultVal
+				continue
+			}
+			if isNil || tag.attrs {
 				continue
 			}
-			_, enum = tagAttrs[attrEnum]
 		}
-		m[key] = fieldValue(field, enum)
+
+		switch {
+		case isFormScalar(field):
+			m[key] = formatFormValue(field, tag.layout)
+		case field.Kind() == reflect.Struct:
+			if err := structToForm(m, field, tagName, key, brackets || tag.brackets); err != nil {
+				return err
+			}
+		case field.Kind() == reflect.Map:
+			mapToForm(m, field, key, brackets || tag.brackets, tag.layout)
+		case (field.Kind() == reflect.Slice || field.Kind() == reflect.Array) && field.Type().Elem().Kind() != reflect.Uint8:
+			if tag.explode {
+				m[key] = formValuesOf(field, tag.layout)
+			} else {
+				m[key] = joinFormValues(field, tag)
+			}
+		default:
+			m[key] = field.Interface()
+		}
 	}
-	return m, nil
+	return nil
+}
+
+func mapToForm(m map[string]interface{}, field reflect.Value, prefix string, brackets bool, layout string) {
+	for _, mapKey := range field.MapKeys() {
+		key := nestedFormKey(prefix, fmt.Sprintf("%v", mapKey.Interface()), brackets)
+		m[key] = formatFormValue(field.MapIndex(mapKey), layout)
+	}
+}
+
+func nestedFormKey(prefix, key string, brackets bool) string {
+	switch {
+	case prefix == "":
+		return key
+	case brackets:
+		return prefix + "[" + key + "]"
+	default:
+		return prefix + "." + key
+	}
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isFormScalar reports whether the value is formatted as a single string rather than reflected into, that is
+// time.Time and encoding.TextMarshaler.
+func isFormScalar(val reflect.Value) bool {
+	return isFormScalarType(val.Type())
+}
+
+func isFormScalarType(typ reflect.Type) bool {
+	return typ == timeType || typ.Implements(textMarshalerType) || reflect.PtrTo(typ).Implements(textMarshalerType)
+}
+
+// formatFormValue formats a single value, time.Time is formatted by layout if it is not empty.
+func formatFormValue(val reflect.Value, layout string) string {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return ""
+		}
+		val = val.Elem()
+	}
+	if val.Type() == timeType && layout != "" {
+		return val.Interface().(time.Time).Format(layout)
+	}
+	var marshaler encoding.TextMarshaler
+	if m, ok := val.Interface().(encoding.TextMarshaler); ok {
+		marshaler = m
+	} else if reflect.PtrTo(val.Type()).Implements(textMarshalerType) {
+		ptr := reflect.New(val.Type())
+		ptr.Elem().Set(val)
+		marshaler = ptr.Interface().(encoding.TextMarshaler)
+	}
+	if marshaler != nil {
+		if text, err := marshaler.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	return fmt.Sprintf("%v", val.Interface())
+}
+
+func formValuesOf(field reflect.Value, layout string) []string {
+	vs := make([]string, field.Len())
+	for i := range vs {
+		vs[i] = formatFormValue(field.Index(i), layout)
+	}
+	return vs
+}
+
+func joinFormValues(field reflect.Value, tag formTag) string {
+	separator := ","
+	if tag.enum {
+		separator = "|"
+	}
+	return strings.Join(formValuesOf(field, tag.layout), separator)
 }
 
 func fieldValue(field reflect.Value, enum bool) interface{} {
@@ -130,6 +328,9 @@ func fieldValue(field reflect.Value, enum bool) interface{} {
 	case reflect.Array:
 		fallthrough
 	case reflect.Slice:
+		if field.Len() == 0 {
+			return ""
+		}
 		stringBuilder := strings.Builder{}
 		for i := 0; i < field.Len(); i++ {
 			stringBuilder.WriteString(fmt.Sprintf("%v%s", field.Index(i), separator))
@@ -139,3 +340,290 @@ func fieldValue(field reflect.Value, enum bool) interface{} {
 		return field.Interface()
 	}
 }
+
+// decodeForm decodes url encoded form into v, which should be a pointer to struct or map.
+// It is the reverse of formToMap, and follows the same tag attributes: fields with `default` are set to the default
+// value if they are absent, absent `required` fields are errors, slices are split by comma, or pipe if `enum`, and
+// repeated keys are collected into slices. Embedded and nested structs, maps, time.Time with `layout` and
+// encoding.TextUnmarshaler are decoded from the keys formToMap flattens them into.
+func decodeForm(data []byte, v interface{}, tagName string) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return fmt.Errorf("decode %s into non-pointer %T", tagName, v)
+	}
+	val = val.Elem()
+	switch val.Kind() {
+	case reflect.Map:
+		return reflectFormToMap(values, val)
+	case reflect.Struct:
+		return reflectFormToStruct(values, val, tagName)
+	case reflect.Interface:
+		m := make(map[string]interface{})
+		if err = reflectFormToMap(values, reflect.ValueOf(m)); err != nil {
+			return err
+		}
+		val.Set(reflect.ValueOf(m))
+		return nil
+	default:
+		return fmt.Errorf("unsupported %s type: %s", tagName, val.Type().String())
+	}
+}
+
+func reflectFormToMap(values url.Values, val reflect.Value) error {
+	typ := val.Type()
+	if typ.Key().Kind() != reflect.String {
+		return fmt.Errorf("unsupported map key type: %s", typ.Key().String())
+	}
+	if val.IsNil() {
+		val.Set(reflect.MakeMap(typ))
+	}
+	for key, vs := range values {
+		elem := reflect.New(typ.Elem()).Elem()
+		var err error
+		switch {
+		case elem.Kind() == reflect.Interface && len(vs) == 1:
+			elem.Set(reflect.ValueOf(vs[0]))
+		case elem.Kind() == reflect.Interface:
+			elem.Set(reflect.ValueOf(vs))
+		case elem.Kind() == reflect.Slice && !isFormScalarType(elem.Type()):
+			err = setSliceValue(elem, vs, "")
+		default:
+			err = setFieldValue(elem, vs[0], "")
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value of key `%s`: %v", key, err)
+		}
+		val.SetMapIndex(reflect.ValueOf(key).Convert(typ.Key()), elem)
+	}
+	return nil
+}
+
+func reflectFormToStruct(values url.Values, val reflect.Value, tagName string) error {
+	_, err := formToStruct(values, val, tagName, "", false)
+	return err
+}
+
+// formToStruct is the reverse of structToForm, it reports whether any key of the struct is present in values.
+func formToStruct(values url.Values, val reflect.Value, tagName string, prefix string, brackets bool) (bool, error) {
+	found := false
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		structField := val.Type().Field(i)
+		if structField.Tag.Get(tagName) == "-" {
+			continue
+		}
+		tag := parseFormTag(structField, tagName)
+
+		typ := structField.Type
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		embedded := structField.Anonymous && structField.Tag.Get(tagName) == ""
+		if typ.Kind() == reflect.Struct && !isFormScalarType(typ) {
+			key := prefix
+			nestedBrackets := brackets
+			if !embedded {
+				key, nestedBrackets = nestedFormKey(prefix, tag.key, brackets), brackets || tag.brackets
+			}
+			nestedFound, err := nestedFormToStruct(values, field, tagName, key, nestedBrackets)
+			if err != nil {
+				return found, err
+			}
+			found = found || nestedFound
+			continue
+		}
+
+		key := nestedFormKey(prefix, tag.key, brackets)
+		if typ.Kind() == reflect.Map {
+			mapFound, err := formToMapField(values, field, key, brackets || tag.brackets, tag.layout)
+			if err != nil {
+				return found, fmt.Errorf("invalid value of field `%s`: %v", structField.Name, err)
+			}
+			found = found || mapFound
+			continue
+		}
+
+		vs := values[key]
+		if len(vs) == 0 || (len(vs) == 1 && vs[0] == "") {
+			if tag.defaultVal != nil {
+				vs = []string{*tag.defaultVal}
+			} else if tag.required {
+				return found, fmt.Errorf("required field `%s` is empty", structField.Name)
+			} else {
+				continue
+			}
+		} else {
+			found = true
+		}
+
+		var err error
+		if kind := typ.Kind(); (kind == reflect.Slice || kind == reflect.Array) && !isFormScalarType(typ) {
+			if len(vs) == 1 && !tag.explode {
+				separator := ","
+				if tag.enum {
+					separator = "|"
+				}
+				vs = strings.Split(vs[0], separator)
+			}
+			err = setSliceValue(field, vs, tag.layout)
+		} else {
+			err = setFieldValue(field, vs[0], tag.layout)
+		}
+		if err != nil {
+			return found, fmt.Errorf("invalid value of field `%s`: %v", structField.Name, err)
+		}
+	}
+	return found, nil
+}
+
+// nestedFormToStruct decodes a nested or embedded struct, pointers are only allocated if any of their keys is present,
+// as nil pointers are omitted by structToForm.
+func nestedFormToStruct(values url.Values, field reflect.Value, tagName string, prefix string, brackets bool) (bool, error) {
+	if field.Kind() != reflect.Ptr {
+		return formToStruct(values, field, tagName, prefix, brackets)
+	}
+	elem := reflect.New(field.Type().Elem())
+	var found bool
+	var err error
+	if elem.Elem().Kind() == reflect.Ptr {
+		found, err = nestedFormToStruct(values, elem.Elem(), tagName, prefix, brackets)
+	} else {
+		found, err = formToStruct(values, elem.Elem(), tagName, prefix, brackets)
+	}
+	if !found {
+		return false, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	field.Set(elem)
+	return true, nil
+}
+
+// formToMapField is the reverse of mapToForm, it collects the keys of `prefix.key` or `prefix[key]`.
+func formToMapField(values url.Values, field reflect.Value, prefix string, brackets bool, layout string) (bool, error) {
+	typ := field.Type()
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Key().Kind() != reflect.String {
+		return false, fmt.Errorf("unsupported map key type: %s", typ.Key().String())
+	}
+	m := reflect.MakeMap(typ)
+	for key, vs := range values {
+		var mapKey string
+		switch {
+		case brackets && strings.HasPrefix(key, prefix+"[") && strings.HasSuffix(key, "]"):
+			mapKey = key[len(prefix)+1 : len(key)-1]
+		case !brackets && strings.HasPrefix(key, prefix+"."):
+			mapKey = key[len(prefix)+1:]
+		default:
+			continue
+		}
+		elem := reflect.New(typ.Elem()).Elem()
+		if err := setFieldValue(elem, vs[0], layout); err != nil {
+			return true, err
+		}
+		m.SetMapIndex(reflect.ValueOf(mapKey).Convert(typ.Key()), elem)
+	}
+	if m.Len() == 0 {
+		return false, nil
+	}
+	if field.Kind() == reflect.Ptr {
+		ptr := reflect.New(typ)
+		ptr.Elem().Set(m)
+		m = ptr
+	}
+	field.Set(m)
+	return true, nil
+}
+
+func setSliceValue(field reflect.Value, vs []string, layout string) error {
+	if field.Kind() == reflect.Array {
+		if len(vs) > field.Len() {
+			return fmt.Errorf("%d values overflow %s", len(vs), field.Type().String())
+		}
+		for i := range vs {
+			if err := setFieldValue(field.Index(i), vs[i], layout); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	slice := reflect.MakeSlice(field.Type(), len(vs), len(vs))
+	for i := range vs {
+		if err := setFieldValue(slice.Index(i), vs[i], layout); err != nil {
+			return err
+		}
+	}
+	field.Set(slice)
+	return nil
+}
+
+// setFieldValue parses a single value, time.Time is parsed by layout, RFC 3339 by default, and
+// encoding.TextUnmarshaler by UnmarshalText.
+func setFieldValue(field reflect.Value, s string, layout string) error {
+	if field.Type() == timeType {
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if field.Kind() != reflect.Ptr && reflect.PtrTo(field.Type()).Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Ptr:
+		elem := reflect.New(field.Type().Elem())
+		if err := setFieldValue(elem.Elem(), s, layout); err != nil {
+			return err
+		}
+		field.Set(elem)
+	case reflect.Interface:
+		if field.NumMethod() > 0 {
+			return fmt.Errorf("unsupported type: %s", field.Type().String())
+		}
+		field.Set(reflect.ValueOf(s))
+	default:
+		return fmt.Errorf("unsupported type: %s", field.Type().String())
+	}
+	return nil
+}
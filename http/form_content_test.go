@@ -0,0 +1,377 @@
+package http
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type formTarget struct {
+	Name    string   `form:"name,required"`
+	Page    int      `form:"page,default=1"`
+	Size    *uint    `form:"size"`
+	Rate    float64  `form:"rate,omitempty"`
+	Enabled bool     `form:"enabled"`
+	Tags    []string `form:"tags"`
+	Status  []string `form:"status,enum"`
+	IDs     []int    `form:"ids,omitempty"`
+	Note    string
+	Ignored string `form:"-"`
+	private string
+}
+
+func Test_decodeForm(t *testing.T) {
+	size := uint(20)
+	tests := []struct {
+		name    string
+		data    string
+		target  interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name:   "test struct",
+			data:   "name=abc&page=2&size=20&rate=0.5&enabled=true&tags=a,b&status=on|off&ids=1&ids=2&note=hi",
+			target: &formTarget{},
+			want: &formTarget{
+				Name: "abc", Page: 2, Size: &size, Rate: 0.5, Enabled: true,
+				Tags: []string{"a", "b"}, Status: []string{"on", "off"}, IDs: []int{1, 2}, Note: "hi",
+			},
+		},
+		{
+			name:   "test default",
+			data:   "name=abc",
+			target: &formTarget{},
+			want:   &formTarget{Name: "abc", Page: 1},
+		},
+		{
+			name:    "test required",
+			data:    "page=1",
+			target:  &formTarget{},
+			wantErr: true,
+		},
+		{
+			name:    "test invalid value",
+			data:    "name=abc&page=first",
+			target:  &formTarget{},
+			wantErr: true,
+		},
+		{
+			name:   "test ignored field",
+			data:   "name=abc&-=x&ignored=y",
+			target: &formTarget{},
+			want:   &formTarget{Name: "abc", Page: 1},
+		},
+		{
+			name:   "test string map",
+			data:   "access_token=abc&expires_in=3600",
+			target: &map[string]string{},
+			want:   &map[string]string{"access_token": "abc", "expires_in": "3600"},
+		},
+		{
+			name:   "test interface map",
+			data:   "a=1&b=2&b=3",
+			target: &map[string]interface{}{},
+			want:   &map[string]interface{}{"a": "1", "b": []string{"2", "3"}},
+		},
+		{
+			name:   "test int map",
+			data:   "a=1&b=2",
+			target: &map[string]int{},
+			want:   &map[string]int{"a": 1, "b": 2},
+		},
+		{
+			name:    "test non-pointer",
+			data:    "a=1",
+			target:  map[string]string{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := decodeForm([]byte(tt.data), tt.target, formTagName)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("decodeForm() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && !reflect.DeepEqual(tt.target, tt.want) {
+				t.Errorf("decodeForm() got = %+v, want %+v", tt.target, tt.want)
+			}
+		})
+	}
+}
+
+type FormPaging struct {
+	Page int `form:"page"`
+	Size int `form:"size,omitempty"`
+}
+
+type FormRange struct {
+	From time.Time `form:"from,layout=2006-01-02"`
+	To   time.Time `form:"to,omitempty"`
+}
+
+type richForm struct {
+	FormPaging
+	*FormRange
+	Keyword string             `form:"q"`
+	Window  FormRange          `form:"window"`
+	Opt     *FormRange         `form:"opt"`
+	Filter  richFilter         `form:"filter,brackets"`
+	IP      net.IP             `form:"ip"`
+	Nets    []net.IP           `form:"nets,explode"`
+	Names   []string           `form:"names,explode"`
+	When    *time.Time         `form:"when"`
+	Count   *int               `form:"count"`
+	Missing *int               `form:"missing"`
+	Any     interface{}        `form:"-"`
+	Extra   map[string]float64 `form:"extra"`
+}
+
+type richFilter struct {
+	Owner  string            `form:"owner"`
+	Labels map[string]string `form:"labels"`
+}
+
+func Test_formRoundTrip(t *testing.T) {
+	type roundTrip struct {
+		Name    string   `form:"name,required"`
+		Page    int      `form:"page,default=1"`
+		Size    *uint    `form:"size"`
+		Rate    float64  `form:"rate,omitempty"`
+		Enabled bool     `form:"enabled"`
+		Tags    []string `form:"tags"`
+		Status  []string `form:"status,enum"`
+		IDs     []int    `form:"ids,omitempty"`
+		Note    string
+	}
+	size := uint(20)
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	when := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	count := 0
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{
+			name: "test full",
+			v: roundTrip{
+				Name: "Bob John", Page: 3, Size: &size, Rate: 1.5, Enabled: true,
+				Tags: []string{"a", "b"}, Status: []string{"on", "off"}, IDs: []int{1, 2}, Note: "a&b=c",
+			},
+		},
+		{
+			name: "test defaults",
+			v:    roundTrip{Name: "abc", Page: 1, Tags: []string{"x"}, Status: []string{"on"}},
+		},
+		{
+			name: "test rich",
+			v: richForm{
+				FormPaging: FormPaging{Page: 2},
+				Keyword:    "go",
+				Window:     FormRange{From: day, To: when},
+				Filter:     richFilter{Owner: "bob", Labels: map[string]string{"env": "prod"}},
+				IP:         net.IPv4(10, 0, 0, 1),
+				Nets:       []net.IP{net.IPv4(10, 0, 0, 2), net.IPv4(10, 0, 0, 3)},
+				Names:      []string{"a,b", "c"},
+				When:       &when,
+				Count:      &count,
+				Extra:      map[string]float64{"ratio": 0.5},
+			},
+		},
+		{
+			name: "test rich pointers",
+			v: richForm{
+				FormRange: &FormRange{From: day, To: when},
+				Opt:       &FormRange{From: day},
+			},
+		},
+	}
+	resolver := &contentTypeForm{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := resolver.Marshal(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := reflect.New(reflect.TypeOf(tt.v))
+			if err = resolver.Unmarshal(data, got.Interface()); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Elem().Interface(), tt.v) {
+				t.Errorf("round trip of %s got = %+v, want %+v", data, got.Elem().Interface(), tt.v)
+			}
+		})
+	}
+}
+
+type Paging struct {
+	Page int `query:"page"`
+	Size int `query:"size,omitempty"`
+}
+
+type Range struct {
+	From time.Time `query:"from,layout=2006-01-02"`
+	To   time.Time `query:"to,omitempty"`
+}
+
+func Test_encodeFormRich(t *testing.T) {
+	day := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	count, active := 0, false
+	tests := []struct {
+		name    string
+		query   interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "embedded struct",
+			query: struct {
+				Paging
+				Keyword string `query:"q"`
+			}{Paging: Paging{Page: 2}, Keyword: "go"},
+			want: "page=2&q=go",
+		},
+		{
+			name: "embedded struct pointer",
+			query: struct {
+				*Paging
+				Keyword string `query:"q"`
+			}{Keyword: "go"},
+			want: "q=go",
+		},
+		{
+			name: "nested dotted",
+			query: struct {
+				Range Range `query:"range"`
+			}{Range: Range{From: day, To: day}},
+			want: "range.from=2024-03-01&range.to=2024-03-01T08%3A30%3A00Z",
+		},
+		{
+			name: "nested brackets",
+			query: struct {
+				Filter struct {
+					Owner  string            `query:"owner"`
+					Labels map[string]string `query:"labels"`
+				} `query:"filter,brackets"`
+			}{Filter: struct {
+				Owner  string            `query:"owner"`
+				Labels map[string]string `query:"labels"`
+			}{Owner: "bob", Labels: map[string]string{"env": "prod"}}},
+			want: "filter%5Blabels%5D%5Benv%5D=prod&filter%5Bowner%5D=bob",
+		},
+		{
+			name: "text marshaler",
+			query: struct {
+				IP   net.IP      `query:"ip"`
+				Nets []net.IP    `query:"nets,explode"`
+				When *time.Time  `query:"when"`
+				Any  interface{} `query:"-"`
+			}{IP: net.IPv4(10, 0, 0, 1), Nets: []net.IP{net.IPv4(10, 0, 0, 2), net.IPv4(10, 0, 0, 3)}, When: &day, Any: "x"},
+			want: "ip=10.0.0.1&nets=10.0.0.2&nets=10.0.0.3&when=2024-03-01T08%3A30%3A00Z",
+		},
+		{
+			name: "pointers",
+			query: struct {
+				Count   *int    `query:"count"`
+				Missing *int    `query:"missing"`
+				Name    *string `query:"name,default=anonymous"`
+			}{Count: &count},
+			want: "count=0&name=anonymous",
+		},
+		{
+			name: "pointers to zero values",
+			query: struct {
+				Page   *int  `query:"page,omitempty"`
+				Active *bool `query:"active,omitempty"`
+				Limit  *int  `query:"limit,default=20"`
+				Size   *int  `query:"size,required"`
+			}{Page: &count, Active: &active, Limit: &count, Size: &count},
+			want: "active=false&limit=0&page=0&size=0",
+		},
+		{
+			name: "required nil pointer",
+			query: struct {
+				Name *string `query:"name,required"`
+			}{},
+			wantErr: true,
+		},
+		{
+			name: "explode",
+			query: struct {
+				IDs    []int    `query:"id,explode"`
+				Tags   []string `query:"tags"`
+				Status []string `query:"status,enum"`
+			}{IDs: []int{3, 1, 2}, Tags: []string{"a", "b"}, Status: []string{"on", "off"}},
+			want: "id=3&id=1&id=2&status=on%7Coff&tags=a%2Cb",
+		},
+		{
+			name:  "repeated map values",
+			query: map[string][]string{"b": {"2", "1"}, "a": {"x"}},
+			want:  "a=x&b=2&b=1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := formToMap(tt.query, queryTagName)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("formToMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			// keys are encoded in the same order every time
+			for i := 0; i < 5; i++ {
+				if got := encodeForm(m); got != tt.want {
+					t.Fatalf("encodeForm() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func Test_parseFormTag(t *testing.T) {
+	tests := []struct {
+		name      string
+		tag       reflect.StructTag
+		wantAttrs bool
+		wantRules []validateRule
+	}{
+		{
+			name: "no attribute",
+			tag:  `form:"name"`,
+		},
+		{
+			name:      "rules only",
+			tag:       `form:"name,min=2,max=10"`,
+			wantRules: []validateRule{{name: "min", param: "2"}, {name: "max", param: "10"}},
+		},
+		{
+			name:      "encoding attributes",
+			tag:       `form:"name,omitempty,explode,required_with=Other"`,
+			wantAttrs: true,
+			wantRules: []validateRule{{name: attrOmitEmpty}, {name: "required_with", param: "Other"}},
+		},
+		{
+			name:      "pattern with comma",
+			tag:       `form:"name,required,pattern=^[a-z]{2,4}$"`,
+			wantAttrs: true,
+			wantRules: []validateRule{{name: attrRequired}, {name: attrPattern, param: "^[a-z]{2,4}$"}},
+		},
+		{
+			name:      "unknown attribute",
+			tag:       `form:"name,email"`,
+			wantAttrs: true,
+			wantRules: []validateRule{{name: "email"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseFormTag(reflect.StructField{Name: "Name", Tag: tt.tag}, formTagName)
+			if got.key != "name" || got.attrs != tt.wantAttrs || !reflect.DeepEqual(got.rules, tt.wantRules) {
+				t.Errorf("parseFormTag() = %+v, want attrs %v rules %+v", got, tt.wantAttrs, tt.wantRules)
+			}
+		})
+	}
+}
@@ -1,17 +1,42 @@
 package http
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/url"
 	"runtime/debug"
+	"strconv"
+	"time"
 )
 
 // Recovery returns a middleware that recovery any panic fired by pending middlewares and save it as error
-// in Response
+// in Response. The panic is logged into DefaultLogger.
 func Recovery() HandlerFunc {
+	return RecoveryWithLogger(nil)
+}
+
+// RecoveryWithLogger is Recovery which logs the panic and its stacktrace into logger at error level,
+// DefaultLogger is used if logger is nil.
+func RecoveryWithLogger(logger *slog.Logger) HandlerFunc {
 	return func(ctx *Context) {
 		defer func() {
 			if r := recover(); r != nil {
-				fmt.Println("panic recovered\nstacktrace: \n" + string(debug.Stack()))
+				l := logger
+				if l == nil {
+					l = DefaultLogger
+				}
+				l.ErrorContext(ctx.Context, "panic recovered",
+					slog.String("method", ctx.Method),
+					slog.String("path_template", "/"+ctx.Request.Path),
+					slog.Any("panic", r),
+					slog.String("stacktrace", string(debug.Stack())))
 				var ok bool
 				var err error
 				err, ok = r.(error)
@@ -25,3 +50,197 @@ func Recovery() HandlerFunc {
 		ctx.Next()
 	}
 }
+
+// BackoffFunc returns how long to wait before the given retry attempt, attempt starts from 1.
+type BackoffFunc func(attempt int) time.Duration
+
+// ConstantBackoff returns a BackoffFunc which always waits d.
+func ConstantBackoff(d time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		return d
+	}
+}
+
+// ExponentialBackoff returns a BackoffFunc which doubles the waiting duration from base on every attempt until max
+// is reached. A random jitter of up to half of the duration is applied to avoid retry storms.
+func ExponentialBackoff(base, max time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		if half := int64(d / 2); half > 0 {
+			d = time.Duration(half + rand.Int63n(half))
+		}
+		return d
+	}
+}
+
+// DefaultRetryStatusCodes are the response status codes retried by Retry if RetryConfig.StatusCodes is empty.
+var DefaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+type RetryConfig struct {
+	// MaxAttempts is the max times the pending handlers will be executed, including the first attempt.
+	// Default is 3.
+	MaxAttempts int
+	// Backoff decides how long to wait between two attempts. Default is ExponentialBackoff(100ms, 10s).
+	Backoff BackoffFunc
+	// StatusCodes are the response status codes which should be retried. Default is DefaultRetryStatusCodes.
+	StatusCodes []int
+}
+
+// Retry returns a middleware that re-runs the pending handlers, including the HTTP request itself, when a transport
+// error or one of the configured status codes comes back.
+// A Retry-After header in response takes precedence over the backoff. Retrying stops once Context is done.
+//
+// Request body which is an io.Reader will be buffered in memory if it is not an io.Seeker, so it can be sent again,
+// and so are the file Readers of Multipart body.
+func Retry(config RetryConfig) HandlerFunc {
+	maxAttempts := config.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 3
+	}
+	backoff := config.Backoff
+	if backoff == nil {
+		backoff = ExponentialBackoff(100*time.Millisecond, 10*time.Second)
+	}
+	statusCodes := config.StatusCodes
+	if len(statusCodes) == 0 {
+		statusCodes = DefaultRetryStatusCodes
+	}
+	retryStatus := make(map[int]struct{}, len(statusCodes))
+	for _, code := range statusCodes {
+		retryStatus[code] = struct{}{}
+	}
+
+	return func(ctx *Context) {
+		rewind, err := rewindableBody(ctx.Request)
+		if err != nil {
+			ctx.Abort()
+			ctx.Response.ErrorSave(err)
+			return
+		}
+		done := context.Background().Done()
+		if ctx.Context != nil {
+			done = ctx.Context.Done()
+		}
+
+		index := ctx.index
+		for attempt := 1; ; attempt++ {
+			if attempt > 1 {
+				if err = rewind(); err != nil {
+					ctx.Response.ErrorSave(err)
+					return
+				}
+				ctx.index = index
+				ctx.Response.ErrorSave(nil)
+				ctx.Response.SetRaw(nil)
+			}
+			ctx.attempt = attempt
+			ctx.Next()
+
+			if attempt >= maxAttempts || !shouldRetry(ctx, retryStatus) {
+				return
+			}
+			wait := backoff(attempt)
+			if d, ok := retryAfter(ctx.Response.HttpResponse()); ok {
+				wait = d
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case <-done:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+func shouldRetry(ctx *Context, retryStatus map[int]struct{}) bool {
+	if ctx.Context != nil && ctx.Context.Err() != nil {
+		return false
+	}
+	var urlErr *url.Error
+	if errors.As(ctx.Response.Error(), &urlErr) {
+		return true
+	}
+	if rsp := ctx.Response.HttpResponse(); rsp != nil {
+		_, ok := retryStatus[rsp.StatusCode]
+		return ok
+	}
+	return false
+}
+
+// retryAfter parses the Retry-After header, which is either delay seconds or an HTTP date.
+func retryAfter(rsp *http.Response) (time.Duration, bool) {
+	if rsp == nil {
+		return 0, false
+	}
+	value := rsp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// rewindableBody makes sure Request body could be read again, it returns a function that resets the body to where
+// it was when rewindableBody is called.
+func rewindableBody(req *Request) (func() error, error) {
+	noop := func() error { return nil }
+	var m *Multipart
+	switch body := req.Body.(type) {
+	case *Multipart:
+		m = body
+	case Multipart:
+		m = &body
+	}
+	if m != nil {
+		rewindable, err := m.rewindable()
+		if err != nil {
+			return nil, err
+		}
+		// file Readers are rewound every time the body is written
+		req.Body = rewindable
+		return noop, nil
+	}
+	r, ok := req.Body.(io.Reader)
+	if !ok {
+		return noop, nil
+	}
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		reader := bytes.NewReader(b)
+		req.Body = reader
+		seeker = reader
+	}
+	offset, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		_, err := seeker.Seek(offset, io.SeekStart)
+		return err
+	}, nil
+}
@@ -0,0 +1,176 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+func TestRetry(t *testing.T) {
+	type reqBody struct {
+		Name string `json:"name"`
+	}
+	tests := []struct {
+		name         string
+		config       RetryConfig
+		failures     int32
+		status       int
+		retryAfter   string
+		body         func() interface{}
+		timeout      time.Duration
+		wantRequests int32
+		wantStatus   int
+		wantErr      bool
+	}{
+		{
+			name:         "succeed after retry",
+			config:       RetryConfig{Backoff: ConstantBackoff(time.Millisecond)},
+			failures:     2,
+			status:       http.StatusServiceUnavailable,
+			wantRequests: 3,
+			wantStatus:   http.StatusOK,
+		},
+		{
+			name:         "max attempts reached",
+			config:       RetryConfig{MaxAttempts: 2, Backoff: ConstantBackoff(time.Millisecond)},
+			failures:     5,
+			status:       http.StatusBadGateway,
+			wantRequests: 2,
+			wantStatus:   http.StatusBadGateway,
+			wantErr:      true,
+		},
+		{
+			name:         "status not retryable",
+			config:       RetryConfig{Backoff: ConstantBackoff(time.Millisecond)},
+			failures:     5,
+			status:       http.StatusInternalServerError,
+			wantRequests: 1,
+			wantStatus:   http.StatusInternalServerError,
+			wantErr:      true,
+		},
+		{
+			name:         "custom status codes",
+			config:       RetryConfig{Backoff: ConstantBackoff(time.Millisecond), StatusCodes: []int{http.StatusInternalServerError}},
+			failures:     1,
+			status:       http.StatusInternalServerError,
+			wantRequests: 2,
+			wantStatus:   http.StatusOK,
+		},
+		{
+			name:         "retry after",
+			config:       RetryConfig{Backoff: ConstantBackoff(time.Hour)},
+			failures:     1,
+			status:       http.StatusTooManyRequests,
+			retryAfter:   "0",
+			wantRequests: 2,
+			wantStatus:   http.StatusOK,
+		},
+		{
+			name:     "non-seekable body",
+			config:   RetryConfig{Backoff: ConstantBackoff(time.Millisecond)},
+			failures: 1,
+			status:   http.StatusServiceUnavailable,
+			body: func() interface{} {
+				return ioutil.NopCloser(strings.NewReader(`{"name":"abc"}`))
+			},
+			wantRequests: 2,
+			wantStatus:   http.StatusOK,
+		},
+		{
+			name:         "context canceled",
+			config:       RetryConfig{Backoff: ConstantBackoff(time.Hour)},
+			failures:     5,
+			status:       http.StatusServiceUnavailable,
+			timeout:      50 * time.Millisecond,
+			wantRequests: 1,
+			wantStatus:   http.StatusServiceUnavailable,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := atomic.Int32{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := requests.Inc()
+				body, _ := ioutil.ReadAll(r.Body)
+				if tt.body != nil && string(body) != `{"name":"abc"}` {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				if n <= tt.failures {
+					if tt.retryAfter != "" {
+						w.Header().Set("Retry-After", tt.retryAfter)
+					}
+					w.WriteHeader(tt.status)
+					return
+				}
+				_, _ = w.Write([]byte(`{"name":"abc"}`))
+			}))
+			defer server.Close()
+
+			req := Req().WithHostName(server.URL).WithPath("retry").Use(Retry(tt.config))
+			if tt.body != nil {
+				req.WithBody(tt.body())
+			}
+			if tt.timeout > 0 {
+				ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+				defer cancel()
+				req.WithContext(ctx)
+			}
+			rsp := &DefaultResponse{Data: &reqBody{}}
+			req.Post(rsp)
+			if (rsp.Error() != nil) != tt.wantErr {
+				t.Errorf("Retry() error = %v, wantErr %v", rsp.Error(), tt.wantErr)
+				return
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("Retry() requests = %d, want %d", got, tt.wantRequests)
+			}
+			if rsp.HttpResponse() == nil || rsp.HttpResponse().StatusCode != tt.wantStatus {
+				t.Errorf("Retry() response = %v, want status %d", rsp.HttpResponse(), tt.wantStatus)
+			}
+		})
+	}
+}
+
+func Test_retryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   time.Duration
+		wantOk bool
+	}{
+		{name: "empty", header: "", wantOk: false},
+		{name: "seconds", header: "2", want: 2 * time.Second, wantOk: true},
+		{name: "past date", header: "Wed, 21 Oct 2015 07:28:00 GMT", want: 0, wantOk: true},
+		{name: "invalid", header: "soon", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rsp := &http.Response{Header: http.Header{}}
+			if tt.header != "" {
+				rsp.Header.Set("Retry-After", tt.header)
+			}
+			got, ok := retryAfter(rsp)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("retryAfter() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(100*time.Millisecond, time.Second)
+	for attempt := 1; attempt <= 10; attempt++ {
+		d := backoff(attempt)
+		if d < 50*time.Millisecond || d > time.Second {
+			t.Errorf("ExponentialBackoff() attempt %d = %v, out of range", attempt, d)
+		}
+	}
+}
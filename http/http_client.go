@@ -2,6 +2,7 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -18,6 +19,9 @@ func doHttpReq(ctx *Context) {
 	req := ctx.Request
 
 	rsp := ctx.Response
+	// result of sending the HTTP request, which is reported to load balancer
+	var sent *http.Response
+	var sendErr error
 	errHandle := func(err error) {
 		rsp.ErrorSave(err)
 	}
@@ -32,18 +36,30 @@ func doHttpReq(ctx *Context) {
 		acceptType = ContentTypeJson
 	}
 
-	contentTypeResolver, ok := contentTypeRegistry[contentType]
+	contentTypeResolver, ok := req.client.contentResolver(mediaType(contentType))
 	if !ok {
 		errHandle(fmt.Errorf("unrecoginzed content type %s", contentType))
 		return
 	}
 
-	acceptTypeResolver, ok := contentTypeRegistry[acceptType]
+	acceptTypeResolver, ok := acceptResolver(req.client, acceptType)
 	if !ok {
 		errHandle(fmt.Errorf("unrecognized accept type %s", acceptType))
 		return
 	}
 
+	if req.balancer != nil {
+		host, release, err := req.balancer.pick(ctx)
+		if err != nil {
+			errHandle(err)
+			return
+		}
+		req.HostName = host
+		defer func() {
+			release(sendErr, sent)
+		}()
+	}
+
 	reqUrl, err := GetUrl(req)
 	if err != nil {
 		errHandle(err)
@@ -51,28 +67,61 @@ func doHttpReq(ctx *Context) {
 	}
 
 	var body io.Reader
-	if req.Body != nil {
-		if r, ok := req.Body.(io.Reader); !ok {
-			var bodyBytes []byte
-			var marshalErr error
-
-			if b, ok := req.Body.([]byte); ok {
-				bodyBytes = b
-			} else {
-				bodyBytes, marshalErr = contentTypeResolver.Marshal(req.Body)
+	// streamed body decides its own content type, ie: multipart boundary
+	var streamContentType string
+	switch b := req.Body.(type) {
+	case nil:
+	case io.Reader:
+		body = b
+	case []byte:
+		body = bytes.NewReader(b)
+	default:
+		if streamer, ok := contentTypeResolver.(StreamMarshaler); ok {
+			stream, streamType, marshalErr := streamer.MarshalStream(req.Body)
+			if marshalErr != nil {
+				errHandle(marshalErr)
+				return
 			}
+			body, streamContentType = stream, streamType
+		} else {
+			bodyBytes, marshalErr := contentTypeResolver.Marshal(req.Body)
 			if marshalErr != nil {
 				errHandle(marshalErr)
 				return
 			}
 			body = bytes.NewReader(bodyBytes)
-		} else {
-			body = r
 		}
 	}
-	httpRequest, err := http.NewRequestWithContext(ctx.Context, ctx.Method, reqUrl, body)
+	ctx.requestBody, ctx.responseBody = nil, nil
+	if reader, ok := body.(*bytes.Reader); ok && ctx.captureBody > 0 {
+		// the body is captured before being compressed
+		ctx.requestBody = newCaptureBuffer(ctx.captureBody)
+		_, _ = io.Copy(ctx.requestBody, io.NewSectionReader(reader, 0, reader.Size()))
+	}
+	var compressed bool
+	if _, encoded := req.Headers[ContentEncodingHeader]; !encoded {
+		compressedBody, ok, compressErr := req.Compression.compress(body)
+		if compressErr != nil {
+			if closer, ok := body.(io.Closer); ok && streamContentType != "" {
+				_ = closer.Close()
+			}
+			errHandle(compressErr)
+			return
+		}
+		body, compressed = compressedBody, ok
+	}
+	reqCtx := ctx.Context
+	ctx.trace = nil
+	if reqCtx != nil {
+		ctx.trace, reqCtx = newClientTrace(reqCtx)
+	}
+	httpRequest, err := http.NewRequestWithContext(reqCtx, ctx.Method, reqUrl, body)
 
 	if err != nil {
+		// streamed and compressed bodies are pipes created here, closing them stops their writing goroutines
+		if closer, ok := body.(io.Closer); ok && (streamContentType != "" || compressed) {
+			_ = closer.Close()
+		}
 		errHandle(err)
 		return
 	}
@@ -82,37 +131,100 @@ func doHttpReq(ctx *Context) {
 			httpRequest.Header.Add(key, value)
 		}
 	}
+	if streamContentType != "" {
+		httpRequest.Header.Set(ContentTypeHeader, streamContentType)
+	}
+	if compressed {
+		httpRequest.Header.Set(ContentEncodingHeader, req.Compression.Encoding)
+	}
+	if ctx.captureBody > 0 && ctx.requestBody == nil && !compressed && httpRequest.Body != nil && httpRequest.Body != http.NoBody {
+		ctx.requestBody = newCaptureBuffer(ctx.captureBody)
+		httpRequest.Body = &teeBody{body: httpRequest.Body, capture: ctx.requestBody}
+	}
 
 	if req.Client == nil {
 		req.Client = http.DefaultClient
 	}
 	httpResponse, err := req.Client.Do(httpRequest)
+	sent, sendErr = httpResponse, err
 	if err != nil {
 		errHandle(err)
 		return
 	}
+	ctx.trace.gotHeaders()
+	if err = decodeResponse(httpResponse); err != nil {
+		_ = httpResponse.Body.Close()
+		rsp.SetRaw(httpResponse)
+		errHandle(err)
+		return
+	}
+	ctx.responseSize = 0
+	counting := &countingBody{ReadCloser: httpResponse.Body, n: &ctx.responseSize, trace: ctx.trace}
+	if ctx.captureBody > 0 {
+		ctx.responseBody = newCaptureBuffer(ctx.captureBody)
+		counting.capture = ctx.responseBody
+	}
+	httpResponse.Body = counting
 	rsp.SetRaw(httpResponse)
+	policy := req.StatusPolicy
+	if policy == nil {
+		policy = DefaultStatusPolicy
+	}
+	isError := policy.isError(httpResponse.StatusCode)
+	if receiver, ok := rsp.(StreamReceiver); ok && !isError {
+		var body io.ReadCloser = httpResponse.Body
+		if ctx.cancel != nil {
+			body = &cancelOnClose{ReadCloser: body, cancel: ctx.cancel}
+			ctx.cancel = nil
+		}
+		receiver.SetBody(body)
+		return
+	}
 	defer func(Body io.ReadCloser) {
 		_ = Body.Close()
 	}(httpResponse.Body)
+	var target interface{} = rsp
+	if t, ok := rsp.(BodyTarget); ok {
+		target = t.Target()
+	}
+	if streamer, ok := acceptTypeResolver.(StreamUnmarshaler); ok && !isError && target != nil {
+		if err = streamer.UnmarshalStream(ctx.Context, httpResponse.Body, target); err != nil {
+			errHandle(err)
+		}
+		return
+	}
 	read, err := ioutil.ReadAll(httpResponse.Body)
 	if err != nil {
 		errHandle(err)
 		return
 	}
-	if len(read) > 0 {
-		if err = acceptTypeResolver.Unmarshal(read, rsp); err != nil {
+	if isError {
+		errHandle(policy.newError(req.client, httpResponse, read))
+		return
+	}
+	if len(read) > 0 && target != nil {
+		if err = acceptTypeResolver.Unmarshal(read, target); err != nil {
 			errHandle(err)
 		}
 	}
 }
 
+// GetUrl builds the URL of Request. HostName takes precedence over ServiceName, which will be resolved into
+// endpoint by the Resolver of Request.
 func GetUrl(req *Request) (string, error) {
-	reqUrl, err := url.Parse(req.HostName)
+	var reqUrl *url.URL
+	var err error
+	if req.HostName == "" && req.ServiceName != "" {
+		reqUrl, err = resolveUrl(req)
+	} else {
+		reqUrl, err = url.Parse(req.HostName)
+	}
 	if err != nil {
 		return "", err
 	}
-	reqUrl.Path = req.Path
+	if reqUrl.Path, reqUrl.RawPath, err = renderPath(req.Path, req.pathParams); err != nil {
+		return "", err
+	}
 	if req.Query != nil {
 		queriesMap, err := formToMap(req.Query, queryTagName)
 		if err != nil {
@@ -122,3 +234,93 @@ func GetUrl(req *Request) (string, error) {
 	}
 	return reqUrl.String(), nil
 }
+
+func resolveUrl(req *Request) (*url.URL, error) {
+	resolver := req.Resolver
+	if resolver == nil {
+		resolver = passthroughResolver{}
+	}
+	port := req.ServicePort
+	if port == 0 {
+		port = defaultPort(req.Scheme())
+	}
+	ctx := context.Background()
+	if req.ctx != nil && req.ctx.Context != nil {
+		ctx = req.ctx.Context
+	}
+	endpoints, err := resolver.Resolve(ctx, req.ServiceName, port)
+	if err != nil {
+		return nil, err
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no endpoint found for service %s", req.ServiceName)
+	}
+	return &url.URL{Scheme: req.Scheme(), Host: endpoints[0].String()}, nil
+}
+
+func defaultPort(scheme string) uint {
+	if scheme == schemeHttps {
+		return 443
+	}
+	return 80
+}
+
+// decodeResponse decompresses response body by its Content-Encoding, as what http.Transport does for gzip, but
+// regardless of DisableCompression. An error is returned if any of the encodings is not registered, rather than
+// handing the compressed body to the resolvers.
+func decodeResponse(rsp *http.Response) error {
+	contentEncoding := rsp.Header.Get(ContentEncodingHeader)
+	if contentEncoding == "" {
+		return nil
+	}
+	body, err := newDecodingBody(rsp.Body, contentEncoding)
+	if body == nil {
+		return err
+	}
+	rsp.Body = body
+	rsp.Header.Del(ContentEncodingHeader)
+	rsp.Header.Del("Content-Length")
+	rsp.ContentLength = -1
+	rsp.Uncompressed = true
+	return nil
+}
+
+// countingBody counts the bytes read from response body, captures them for logs if capture is not nil, and finishes
+// the BodyRead of trace once the body is read to the end or closed.
+type countingBody struct {
+	io.ReadCloser
+	n       *int64
+	capture io.Writer
+	trace   *clientTrace
+}
+
+func (c *countingBody) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	*c.n += int64(n)
+	if c.capture != nil && n > 0 {
+		_, _ = c.capture.Write(p[:n])
+	}
+	if err == io.EOF && c.trace != nil {
+		c.trace.finishBody()
+	}
+	return n, err
+}
+
+func (c *countingBody) Close() error {
+	if c.trace != nil {
+		c.trace.finishBody()
+	}
+	return c.ReadCloser.Close()
+}
+
+// cancelOnClose cancels the context of Request once the streamed response body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"reflect"
 	"testing"
@@ -315,6 +316,13 @@ func Test_doHttpReq(t *testing.T) {
 			fmt.Println("echo server start failed ", err)
 		}
 	}()
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial("tcp", "localhost:8080"); err == nil {
+			_ = conn.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.args.req.ctx.Method = tt.args.method
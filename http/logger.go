@@ -1,37 +1,89 @@
 package http
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 )
 
 var DefaultWriter io.Writer = os.Stdout
 
 type LogFormatterParams struct {
-	Request    *Request
-	Response   Response
-	Method     string
-	Path       string
+	Request  *Request
+	Response Response
+	Method   string
+	// Path is the request path with path parameters filled.
+	Path string
+	// PathTemplate is the path before path parameters are filled, which is of low cardinality.
+	PathTemplate string
+	// Host is the service name of Request, or the host of HostName if service name is absent.
+	Host       string
 	StatusCode int
+	// ErrMessage is the message of error, the raw response body of StatusError is left out.
 	ErrMessage string
-	Timestamp  time.Time
-	Latency    time.Duration
+	// ErrKind classifies the error, ie: status, timeout or transport, see ErrorKind.
+	ErrKind string
+	// ResponseSize is the size of response body read before the handler chain returns, streamed bodies are not
+	// counted.
+	ResponseSize int64
+	Timestamp    time.Time
+	Latency      time.Duration
+	// Timings breaks Latency down into the phases of the last attempt, ie: DNS, Connect and TimeToFirstByte.
+	Timings Timings
+	// Query, headers and bodies are only collected if they are enabled in LoggerConfig, and they are redacted.
+	Query          string
+	RequestHeader  http.Header
+	ResponseHeader http.Header
+	RequestBody    string
+	ResponseBody   string
 }
 
 type LoggerFormatter func(param LogFormatterParams) string
 
 var defaultLogFormatter = func(param LogFormatterParams) string {
+	path := param.Path
+	if param.Query != "" {
+		path += "?" + param.Query
+	}
+	var details strings.Builder
+	if len(param.RequestHeader) > 0 {
+		fmt.Fprintf(&details, " | request headers: %v", param.RequestHeader)
+	}
+	if param.RequestBody != "" {
+		fmt.Fprintf(&details, " | request body: %s", param.RequestBody)
+	}
+	if len(param.ResponseHeader) > 0 {
+		fmt.Fprintf(&details, " | response headers: %v", param.ResponseHeader)
+	}
+	if param.ResponseBody != "" {
+		fmt.Fprintf(&details, " | response body: %s", param.ResponseBody)
+	}
 	if param.ErrMessage != "" {
-		return fmt.Sprintf("[%s] | %10v | %s | %s/%s\n", param.Method, param.Latency, param.ErrMessage, param.Request.HostName, param.Path)
+		return fmt.Sprintf("[%s] | %10v | %s | %s/%s%s\n", param.Method, param.Latency, param.ErrMessage, param.Request.HostName, path, details.String())
 	}
-	return fmt.Sprintf("[%s] | %10v | %d | %s/%s\n", param.Method, param.Latency, param.StatusCode, param.Request.HostName, param.Path)
+	return fmt.Sprintf("[%s] | %10v | %d | %s/%s%s\n", param.Method, param.Latency, param.StatusCode, param.Request.HostName, path, details.String())
 }
 
 type LoggerConfig struct {
 	Formatter LoggerFormatter
 	Output    io.Writer
+	// Logger emits structured records by SlogLogger rather than formatted lines, Formatter and Output are ignored
+	// if it is set.
+	Logger *slog.Logger
+	// LogQuery, LogHeaders and LogBody log the query, the headers and the bodies of request and response, which are
+	// masked by Redaction. Only JSON and form bodies up to MaxBodySize bytes are logged, default is 4KB.
+	LogQuery    bool
+	LogHeaders  bool
+	LogBody     bool
+	MaxBodySize int
+	Redaction   Redaction
 }
 
 // Logger returns a middleware that log into remote.DefaultWriter.
@@ -46,6 +98,15 @@ func LoggerWithFormatter(formatter LoggerFormatter) HandlerFunc {
 }
 
 func LoggerWithConfig(config LoggerConfig) HandlerFunc {
+	if config.Logger != nil {
+		return SlogLogger(config.Logger, SlogOptions{
+			LogQuery:    config.LogQuery,
+			LogHeaders:  config.LogHeaders,
+			LogBody:     config.LogBody,
+			MaxBodySize: config.MaxBodySize,
+			Redaction:   config.Redaction,
+		})
+	}
 	formatter := config.Formatter
 	if formatter == nil {
 		formatter = defaultLogFormatter
@@ -56,30 +117,102 @@ func LoggerWithConfig(config LoggerConfig) HandlerFunc {
 		out = DefaultWriter
 	}
 
+	details := newLogDetails(config.LogQuery, config.LogHeaders, config.LogBody, config.MaxBodySize, config.Redaction)
 	return func(ctx *Context) {
 		start := time.Now()
+		details.prepare(ctx)
 
 		ctx.Next()
 
-		param := LogFormatterParams{
-			Request:  ctx.Request,
-			Response: ctx.Response,
-			Method:   ctx.Method,
-			Path:     ctx.Request.Path,
-		}
+		param := newLogFormatterParams(ctx, start)
+		details.fill(ctx, &param)
+		_, _ = fmt.Fprint(out, formatter(param))
+	}
+}
 
-		param.Timestamp = time.Now()
-		param.Latency = param.Timestamp.Sub(start)
+// newLogFormatterParams collects the result of handler chain started at start, it is shared by the middlewares which
+// observe Requests, ie: Logger and Metrics.
+func newLogFormatterParams(ctx *Context, start time.Time) LogFormatterParams {
+	param := LogFormatterParams{
+		Request:      ctx.Request,
+		Response:     ctx.Response,
+		Method:       ctx.Method,
+		Path:         ctx.Request.renderedPath(),
+		PathTemplate: ctx.Request.Path,
+		Host:         requestHost(ctx.Request),
+		ResponseSize: ctx.responseSize,
+		Timings:      ctx.trace.snapshot(),
+	}
 
-		if ctx.Response.HttpResponse() != nil {
-			param.StatusCode = ctx.Response.HttpResponse().StatusCode
-		}
-		if ctx.Request.err != nil {
-			param.ErrMessage = ctx.Request.err.Error()
-		} else if ctx.Response.Error() != nil {
-			param.ErrMessage = ctx.Response.Error().Error()
-		}
+	param.Timestamp = time.Now()
+	param.Latency = param.Timestamp.Sub(start)
 
-		_, _ = fmt.Fprint(out, formatter(param))
+	if ctx.Response.HttpResponse() != nil {
+		param.StatusCode = ctx.Response.HttpResponse().StatusCode
+	}
+	err := ctx.Request.err
+	if err == nil {
+		err = ctx.Response.Error()
+	}
+	if err != nil {
+		param.ErrMessage = logErrorMessage(err)
+		param.ErrKind = ErrorKind(err)
+	}
+	return param
+}
+
+// logErrorMessage returns the message of err for logs and spans. The raw response body in StatusError is left out,
+// as it is not masked by Redaction, only the status and the error decoded from the body, ie: Problem, are kept.
+func logErrorMessage(err error) string {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return err.Error()
+	}
+	logged := &StatusError{StatusCode: statusErr.StatusCode}
+	if detail, ok := statusErr.Detail.(error); ok {
+		logged.Detail = detail
+	}
+	return strings.Replace(err.Error(), statusErr.Error(), logged.Error(), 1)
+}
+
+func requestHost(req *Request) string {
+	if req.ServiceName != "" {
+		return req.ServiceName
+	}
+	if u, err := url.Parse(req.HostName); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return req.HostName
+}
+
+// ErrorKind classifies the error saved in Response into a low cardinality kind:
+// validation, status, timeout, canceled, circuit_open, rate_limited, transport or other.
+// Empty string is returned if err is nil.
+func ErrorKind(err error) string {
+	var validationErrs ValidationErrors
+	var statusErr *StatusError
+	var urlErr *url.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &validationErrs):
+		return "validation"
+	case errors.As(err, &statusErr):
+		return "status"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, CircuitBreakerOpenError):
+		return "circuit_open"
+	case errors.Is(err, RateLimitExceedError):
+		return "rate_limited"
+	case errors.As(err, &urlErr):
+		if urlErr.Timeout() {
+			return "timeout"
+		}
+		return "transport"
+	default:
+		return "other"
 	}
 }
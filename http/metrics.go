@@ -0,0 +1,354 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the upper bounds in seconds of request latency histogram.
+var DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultSizeBuckets are the upper bounds in bytes of response size histogram.
+var DefaultSizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+
+// Metrics returns a middleware that records the requests into registry:
+//
+//   - http_client_requests_total: counter of finished requests
+//   - http_client_requests_in_flight: gauge of requests being processed
+//   - http_client_request_duration_seconds: histogram of latency
+//   - http_client_response_size_bytes: histogram of response body size
+//   - http_client_request_phase_duration_seconds: histogram of the phases in Timings
+//   - http_client_connections_total: counter of connections used by requests
+//
+// The labels are method, host, path, status and error. host is known before the handler chain runs, so the endpoints
+// picked for a Service are not used, the Service is labeled by its Name, or by its Hosts if it has no Name. path is
+// the path template with a leading slash, status is the status class like 2xx, and error is ErrorKind. Absent status
+// or error is labeled as none. Phases are labeled by phase, which is one of conn_wait, dns, connect, tls, ttfb and
+// body_read, the phases which do not happen are not observed. Connections are labeled by host and reused.
+func Metrics(registry *MetricsRegistry) HandlerFunc {
+	requests := registry.Counter("http_client_requests_total",
+		"Total number of HTTP client requests.", "method", "host", "path", "status", "error")
+	inFlight := registry.Gauge("http_client_requests_in_flight",
+		"Number of HTTP client requests being processed.", "method", "host", "path")
+	latency := registry.Histogram("http_client_request_duration_seconds",
+		"Latency of HTTP client requests in seconds.", DefaultLatencyBuckets, "method", "host", "path", "status", "error")
+	size := registry.Histogram("http_client_response_size_bytes",
+		"Size of HTTP client response bodies in bytes.", DefaultSizeBuckets, "method", "host", "path", "status")
+	phases := registry.Histogram("http_client_request_phase_duration_seconds",
+		"Duration of the phases of HTTP client requests in seconds.", DefaultLatencyBuckets, "method", "host", "path", "phase")
+	connections := registry.Counter("http_client_connections_total",
+		"Total number of connections used by HTTP client requests.", "host", "reused")
+
+	return func(ctx *Context) {
+		start := time.Now()
+		method, host, path := ctx.Method, metricsHost(ctx.Request), "/"+ctx.Request.Path
+		gauge := inFlight.With(method, host, path)
+		gauge.Add(1)
+		defer gauge.Add(-1)
+
+		ctx.Next()
+
+		param := newLogFormatterParams(ctx, start)
+		status, errKind := statusClass(param.StatusCode), param.ErrKind
+		if errKind == "" {
+			errKind = "none"
+		}
+		requests.With(method, host, path, status, errKind).Add(1)
+		latency.With(method, host, path, status, errKind).Observe(param.Latency.Seconds())
+		if param.StatusCode != 0 {
+			size.With(method, host, path, status).Observe(float64(param.ResponseSize))
+		}
+		timings := param.Timings
+		if timings == (Timings{}) {
+			return
+		}
+		connections.With(host, strconv.FormatBool(timings.ConnReused)).Add(1)
+		for _, phase := range []struct {
+			name     string
+			duration time.Duration
+		}{
+			{"conn_wait", timings.ConnWait},
+			{"dns", timings.DNS},
+			{"connect", timings.Connect},
+			{"tls", timings.TLSHandshake},
+			{"ttfb", timings.TimeToFirstByte},
+			{"body_read", timings.BodyRead},
+		} {
+			if phase.duration > 0 {
+				phases.With(method, host, path, phase.name).Observe(phase.duration.Seconds())
+			}
+		}
+	}
+}
+
+// metricsHost returns the host label of req, the balancer of Service is labeled by the name of Service.
+func metricsHost(req *Request) string {
+	if req.balancer != nil {
+		return req.balancer.name
+	}
+	return requestHost(req)
+}
+
+func statusClass(statusCode int) string {
+	if statusCode == 0 {
+		return "none"
+	}
+	return strconv.Itoa(statusCode/100) + "xx"
+}
+
+// MetricsRegistry keeps metrics in memory, and renders them in Prometheus text format as an http.Handler.
+type MetricsRegistry struct {
+	mu       sync.Mutex
+	families []*metricFamily
+	byName   map[string]*metricFamily
+}
+
+// NewMetricsRegistry returns an empty MetricsRegistry.
+func NewMetricsRegistry() *MetricsRegistry {
+	return &MetricsRegistry{byName: make(map[string]*metricFamily)}
+}
+
+type metricFamily struct {
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+	buckets    []float64
+	mu         sync.Mutex
+	series     map[string]*metricSeries
+}
+
+type metricSeries struct {
+	labelValues []string
+	mu          sync.Mutex
+	value       float64
+	counts      []uint64
+	count       uint64
+}
+
+// register returns the existing family of name, it panics if the family is registered with another kind or labels.
+func (r *MetricsRegistry) register(name, help, kind string, buckets []float64, labelNames []string) *metricFamily {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if family, ok := r.byName[name]; ok {
+		if family.kind != kind || strings.Join(family.labelNames, ",") != strings.Join(labelNames, ",") {
+			panic(fmt.Sprintf("metric %s is registered as %s with labels %v", name, family.kind, family.labelNames))
+		}
+		return family
+	}
+	family := &metricFamily{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: labelNames,
+		buckets:    buckets,
+		series:     make(map[string]*metricSeries),
+	}
+	r.families = append(r.families, family)
+	r.byName[name] = family
+	return family
+}
+
+// Counter registers a counter with the given label names, registering the same name again returns the same counter.
+func (r *MetricsRegistry) Counter(name, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{family: r.register(name, help, "counter", nil, labelNames)}
+}
+
+// Gauge registers a gauge with the given label names, registering the same name again returns the same gauge.
+func (r *MetricsRegistry) Gauge(name, help string, labelNames ...string) *GaugeVec {
+	return &GaugeVec{family: r.register(name, help, "gauge", nil, labelNames)}
+}
+
+// Histogram registers a histogram with the given upper bounds of buckets and label names,
+// registering the same name again returns the same histogram.
+func (r *MetricsRegistry) Histogram(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &HistogramVec{family: r.register(name, help, "histogram", sorted, labelNames)}
+}
+
+// with returns the series of the label values, missing values are empty.
+func (f *metricFamily) with(labelValues []string) *metricSeries {
+	values := make([]string, len(f.labelNames))
+	copy(values, labelValues)
+	key := strings.Join(values, "\xff")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	series, ok := f.series[key]
+	if !ok {
+		series = &metricSeries{labelValues: values}
+		if f.kind == "histogram" {
+			series.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = series
+	}
+	return series
+}
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct {
+	family *metricFamily
+}
+
+// With returns the counter of the label values, which are in the order of label names.
+func (c *CounterVec) With(labelValues ...string) *Counter {
+	return &Counter{series: c.family.with(labelValues)}
+}
+
+// Counter is a value that only goes up.
+type Counter struct {
+	series *metricSeries
+}
+
+// Add increases the counter, negative delta is ignored.
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		return
+	}
+	c.series.mu.Lock()
+	c.series.value += delta
+	c.series.mu.Unlock()
+}
+
+// GaugeVec is a gauge partitioned by labels.
+type GaugeVec struct {
+	family *metricFamily
+}
+
+// With returns the gauge of the label values, which are in the order of label names.
+func (g *GaugeVec) With(labelValues ...string) *Gauge {
+	return &Gauge{series: g.family.with(labelValues)}
+}
+
+// Gauge is a value that could go up and down.
+type Gauge struct {
+	series *metricSeries
+}
+
+func (g *Gauge) Add(delta float64) {
+	g.series.mu.Lock()
+	g.series.value += delta
+	g.series.mu.Unlock()
+}
+
+func (g *Gauge) Set(value float64) {
+	g.series.mu.Lock()
+	g.series.value = value
+	g.series.mu.Unlock()
+}
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct {
+	family *metricFamily
+}
+
+// With returns the histogram of the label values, which are in the order of label names.
+func (h *HistogramVec) With(labelValues ...string) *Histogram {
+	return &Histogram{series: h.family.with(labelValues), buckets: h.family.buckets}
+}
+
+// Histogram counts observed values in cumulative buckets.
+type Histogram struct {
+	series  *metricSeries
+	buckets []float64
+}
+
+func (h *Histogram) Observe(value float64) {
+	h.series.mu.Lock()
+	defer h.series.mu.Unlock()
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.series.counts[i]++
+		}
+	}
+	h.series.count++
+	h.series.value += value
+}
+
+// ServeHTTP renders the metrics in Prometheus text format.
+func (r *MetricsRegistry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set(ContentTypeHeader, "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = r.WriteTo(w)
+}
+
+// WriteTo writes the metrics in Prometheus text format into w. Families are in the order they are registered,
+// and series are sorted by label values.
+func (r *MetricsRegistry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	families := append([]*metricFamily(nil), r.families...)
+	r.mu.Unlock()
+
+	var b strings.Builder
+	for _, family := range families {
+		family.writeTo(&b)
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (f *metricFamily) writeTo(b *strings.Builder) {
+	f.mu.Lock()
+	series := make([]*metricSeries, 0, len(f.series))
+	for _, s := range f.series {
+		series = append(series, s)
+	}
+	f.mu.Unlock()
+	sort.Slice(series, func(i, j int) bool {
+		return strings.Join(series[i].labelValues, "\xff") < strings.Join(series[j].labelValues, "\xff")
+	})
+
+	fmt.Fprintf(b, "# HELP %s %s\n", f.name, helpEscaper.Replace(f.help))
+	fmt.Fprintf(b, "# TYPE %s %s\n", f.name, f.kind)
+	for _, s := range series {
+		s.mu.Lock()
+		labels := formatLabels(f.labelNames, s.labelValues, "", "")
+		if f.kind != "histogram" {
+			fmt.Fprintf(b, "%s%s %s\n", f.name, labels, formatFloat(s.value))
+			s.mu.Unlock()
+			continue
+		}
+		for i, bound := range f.buckets {
+			fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, formatLabels(f.labelNames, s.labelValues, "le", formatFloat(bound)), s.counts[i])
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, formatLabels(f.labelNames, s.labelValues, "le", "+Inf"), s.count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", f.name, labels, formatFloat(s.value))
+		fmt.Fprintf(b, "%s_count%s %d\n", f.name, labels, s.count)
+		s.mu.Unlock()
+	}
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	pairs := make([]string, 0, len(names)+1)
+	for i, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, labelEscaper.Replace(values[i])))
+	}
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extraName, extraValue))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/0") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(`{"name":"bob"}`))
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	registry := NewMetricsRegistry()
+	client := NewClient().Use(Metrics(registry))
+	for _, id := range []int{1, 2, 0} {
+		client.Req().WithHostName(server.URL).WithPath("/users/{id}").PathParam("id", id).Get(&DefaultResponse{})
+	}
+	// validation errors never reach middlewares
+	client.Req().WithHostName(server.URL).WithPath("/users/{id}").Get(&DefaultResponse{})
+	// endpoints picked for Service are not used as host
+	named := &Service{Parent: client, Name: "users", Hosts: []string{server.URL}}
+	named.Serve().WithPath("/users/{id}").PathParam("id", 3).Get(&DefaultResponse{})
+	unnamed := &Service{Parent: client, Hosts: []string{server.URL, server.URL + "/"}}
+	unnamed.Serve().WithPath("/users/{id}").PathParam("id", 4).Get(&DefaultResponse{})
+
+	recorder := httptest.NewRecorder()
+	registry.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	got := recorder.Body.String()
+	labels := `method="GET",host="` + host + `",path="/users/{id}"`
+	serviceLabels := `method="GET",host="users",path="/users/{id}"`
+	hostsLabels := `method="GET",host="` + server.URL + "," + server.URL + `/",path="/users/{id}"`
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "type", want: "# TYPE http_client_requests_total counter\n"},
+		{name: "success count", want: "http_client_requests_total{" + labels + `,status="2xx",error="none"} 2` + "\n"},
+		{name: "error count", want: "http_client_requests_total{" + labels + `,status="5xx",error="status"} 1` + "\n"},
+		{name: "in flight", want: "http_client_requests_in_flight{" + labels + "} 0\n"},
+		{name: "service count", want: "http_client_requests_total{" + serviceLabels + `,status="2xx",error="none"} 1` + "\n"},
+		{name: "service in flight", want: "http_client_requests_in_flight{" + serviceLabels + "} 0\n"},
+		{name: "service ttfb", want: "http_client_request_phase_duration_seconds_count{" + serviceLabels + `,phase="ttfb"} 1` + "\n"},
+		{name: "service hosts count", want: "http_client_requests_total{" + hostsLabels + `,status="2xx",error="none"} 1` + "\n"},
+		{name: "latency", want: "http_client_request_duration_seconds_bucket{" + labels + `,status="2xx",error="none",le="+Inf"} 2` + "\n"},
+		{name: "latency count", want: "http_client_request_duration_seconds_count{" + labels + `,status="5xx",error="status"} 1` + "\n"},
+		{name: "size", want: "http_client_response_size_bytes_bucket{" + labels + `,status="2xx",le="100"} 2` + "\n"},
+		{name: "size sum", want: "http_client_response_size_bytes_sum{" + labels + `,status="2xx"} 28` + "\n"},
+		{name: "ttfb", want: "http_client_request_phase_duration_seconds_count{" + labels + `,phase="ttfb"} 3` + "\n"},
+		{name: "connections", want: `http_client_connections_total{host="` + host + `",reused="false"} 1` + "\n"},
+		{name: "reused connections", want: `http_client_connections_total{host="` + host + `",reused="true"} 2` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("metrics want %q in:\n%s", tt.want, got)
+			}
+		})
+	}
+	if ct := recorder.Header().Get(ContentTypeHeader); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("metrics content type = %s", ct)
+	}
+}
+
+func TestMetricsRegistry(t *testing.T) {
+	registry := NewMetricsRegistry()
+	jobs := registry.Counter("jobs_total", "Jobs.\nDone.", "queue")
+	jobs.With(`a"b`).Add(2)
+	jobs.With("a").Add(1)
+	jobs.With("a").Add(-1)
+	registry.Gauge("temperature", "Temperature.").With().Set(-1.5)
+	h := registry.Histogram("size", "Size.", []float64{10, 1}, "kind")
+	h.With("x").Observe(0.5)
+	h.With("x").Observe(5)
+
+	var b strings.Builder
+	if _, err := registry.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP jobs_total Jobs.\nDone.
+# TYPE jobs_total counter
+jobs_total{queue="a"} 1
+jobs_total{queue="a\"b"} 2
+# HELP temperature Temperature.
+# TYPE temperature gauge
+temperature -1.5
+# HELP size Size.
+# TYPE size histogram
+size_bucket{kind="x",le="1"} 1
+size_bucket{kind="x",le="10"} 2
+size_bucket{kind="x",le="+Inf"} 2
+size_sum{kind="x"} 5.5
+size_count{kind="x"} 2
+`
+	if b.String() != want {
+		t.Errorf("WriteTo() got:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
@@ -0,0 +1,169 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const ContentTypeMultipart = "multipart/form-data"
+
+// StreamMarshaler could be implemented by ContentTypeResolver to stream request body instead of buffering it.
+// The returned content type replaces the Content-Type header of Request, ie: to carry the multipart boundary.
+type StreamMarshaler interface {
+	MarshalStream(v interface{}) (body io.ReadCloser, contentType string, err error)
+}
+
+// FilePart is a file in multipart body, the content is read from Reader, or the file at Path if Reader is nil.
+//
+// A Reader which is also an io.Seeker is rewound to its beginning every time the body is written,
+// so that the Request could be retried. Other Readers could only be sent once, unless they are buffered by Retry.
+type FilePart struct {
+	Field string
+	// FileName is the file name in Content-Disposition, default is the base name of Path.
+	FileName string
+	// ContentType of the file, default is application/octet-stream.
+	ContentType string
+	Reader      io.Reader
+	Path        string
+}
+
+// Multipart is the body of multipart/form-data Request.
+// Fields is a struct with `form` tags or a map, just like the body of application/x-www-form-urlencoded Request.
+type Multipart struct {
+	Fields interface{}
+	Files  []FilePart
+}
+
+// WithMultipart sets multipart/form-data body of current Request. The body is streamed when the Request is sent,
+// and the boundary is set into Content-Type automatically.
+func (r *Request) WithMultipart(fields interface{}, files ...FilePart) *Request {
+	r.Body = &Multipart{Fields: fields, Files: files}
+	return r.ContentType(ContentTypeMultipart)
+}
+
+// rewindable returns a copy of m whose file Readers are all io.Seekers, the other Readers are buffered in memory,
+// so that the body could be written again.
+func (m *Multipart) rewindable() (*Multipart, error) {
+	files := make([]FilePart, len(m.Files))
+	copy(files, m.Files)
+	for i, file := range files {
+		if _, ok := file.Reader.(io.Seeker); ok || file.Reader == nil {
+			continue
+		}
+		b, err := ioutil.ReadAll(file.Reader)
+		if err != nil {
+			return nil, err
+		}
+		files[i].Reader = bytes.NewReader(b)
+	}
+	return &Multipart{Fields: m.Fields, Files: files}, nil
+}
+
+type contentTypeMultipart struct{}
+
+func (c *contentTypeMultipart) Marshal(v interface{}) ([]byte, error) {
+	return nil, errors.New("multipart/form-data body could only be marshaled as stream")
+}
+
+func (c *contentTypeMultipart) Unmarshal(bytes []byte, v interface{}) error {
+	return errors.New("unmarshal multipart/form-data is not supported")
+}
+
+func (c *contentTypeMultipart) MarshalStream(v interface{}) (io.ReadCloser, string, error) {
+	var m *Multipart
+	switch body := v.(type) {
+	case *Multipart:
+		m = body
+	case Multipart:
+		m = &body
+	default:
+		m = &Multipart{Fields: v}
+	}
+	var fields map[string]interface{}
+	if m.Fields != nil {
+		var err error
+		if fields, err = formToMap(m.Fields, formTagName); err != nil {
+			return nil, "", err
+		}
+	}
+
+	reader, writer := io.Pipe()
+	mw := multipart.NewWriter(writer)
+	go func() {
+		err := writeMultipart(mw, fields, m.Files)
+		if err == nil {
+			err = mw.Close()
+		}
+		_ = writer.CloseWithError(err)
+	}()
+	return reader, mw.FormDataContentType(), nil
+}
+
+func writeMultipart(mw *multipart.Writer, fields map[string]interface{}, files []FilePart) error {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range formValues(fields[key]) {
+			if err := mw.WriteField(key, value); err != nil {
+				return err
+			}
+		}
+	}
+	for _, file := range files {
+		if err := writeFilePart(mw, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func writeFilePart(mw *multipart.Writer, file FilePart) error {
+	reader := file.Reader
+	if reader == nil {
+		f, err := os.Open(file.Path)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		reader = f
+	} else if seeker, ok := reader.(io.Seeker); ok {
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	fileName := file.FileName
+	if fileName == "" && file.Path != "" {
+		fileName = filepath.Base(file.Path)
+	}
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(file.Field), quoteEscaper.Replace(fileName)))
+	header.Set(ContentTypeHeader, contentType)
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, reader)
+	return err
+}
@@ -0,0 +1,193 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type zeroReader struct {
+	remaining int64
+}
+
+func (z *zeroReader) Read(p []byte) (int, error) {
+	if z.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > z.remaining {
+		p = p[:z.remaining]
+	}
+	for i := range p {
+		p[i] = 0
+	}
+	z.remaining -= int64(len(p))
+	return len(p), nil
+}
+
+func TestRequestWithMultipart(t *testing.T) {
+	type upload struct {
+		Name    string   `form:"name,required"`
+		Version int      `form:"version,default=1"`
+		Tags    []string `form:"tags"`
+	}
+	type part struct {
+		FileName    string
+		ContentType string
+		Size        int64
+		Sum         string
+	}
+	type result struct {
+		Fields map[string]string
+		Files  map[string]part
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reader, err := r.MultipartReader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		res := result{Fields: map[string]string{}, Files: map[string]part{}}
+		for {
+			p, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if p.FileName() == "" {
+				value, _ := ioutil.ReadAll(p)
+				res.Fields[p.FormName()] = string(value)
+				continue
+			}
+			h := sha256.New()
+			n, _ := io.Copy(h, p)
+			res.Files[p.FormName()] = part{
+				FileName:    p.FileName(),
+				ContentType: p.Header.Get(ContentTypeHeader),
+				Size:        n,
+				Sum:         hex.EncodeToString(h.Sum(nil))[:8],
+			}
+		}
+		w.Header().Set(ContentTypeHeader, ContentTypeJson)
+		_, _ = w.Write([]byte(`{"fields":` + toJson(res.Fields) + `,"files":` + toJson(res.Files) + `}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "artifact.txt")
+	if err := ioutil.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		fields  interface{}
+		files   []FilePart
+		want    result
+		wantErr bool
+	}{
+		{
+			name:   "fields and files",
+			fields: upload{Name: "build", Tags: []string{"a", "b"}},
+			files: []FilePart{
+				{Field: "readme", FileName: "README.md", ContentType: "text/markdown", Reader: strings.NewReader("# readme")},
+				{Field: "artifact", Path: path},
+			},
+			want: result{
+				Fields: map[string]string{"name": "build", "version": "1", "tags": "a,b"},
+				Files: map[string]part{
+					"readme":   {FileName: "README.md", ContentType: "text/markdown", Size: 8, Sum: "abe838af"},
+					"artifact": {FileName: "artifact.txt", ContentType: "application/octet-stream", Size: 5, Sum: "2cf24dba"},
+				},
+			},
+		},
+		{
+			name:  "large file",
+			files: []FilePart{{Field: "blob", FileName: "blob.bin", Reader: &zeroReader{remaining: 64 << 20}}},
+			want: result{
+				Fields: map[string]string{},
+				Files:  map[string]part{"blob": {FileName: "blob.bin", ContentType: "application/octet-stream", Size: 64 << 20, Sum: "3b6a07d0"}},
+			},
+		},
+		{
+			name:    "missing file",
+			files:   []FilePart{{Field: "artifact", Path: filepath.Join(t.TempDir(), "missing")}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid fields",
+			fields:  upload{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := result{}
+			rsp := &DefaultResponse{Data: &got}
+			Req().WithHostName(server.URL).WithTimeout(10*time.Second).WithMultipart(tt.fields, tt.files...).Post(rsp)
+			if (rsp.Error() != nil) != tt.wantErr {
+				t.Errorf("Request.WithMultipart() error = %v, wantErr %v", rsp.Error(), tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Request.WithMultipart() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func toJson(v interface{}) string {
+	b, _ := (&contentTypeJson{}).Marshal(v)
+	return string(b)
+}
+
+func TestRequestWithMultipartRetry(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, _ := ioutil.ReadAll(file)
+		received = append(received, string(b))
+		if len(received) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		reader io.Reader
+	}{
+		{name: "seeker", reader: strings.NewReader("hello")},
+		{name: "not seeker", reader: io.MultiReader(strings.NewReader("hello"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received = nil
+			rsp := &DefaultResponse{}
+			Req().WithHostName(server.URL).
+				WithMultipart(nil, FilePart{Field: "file", FileName: "a.txt", Reader: tt.reader}).
+				Use(Retry(RetryConfig{Backoff: ConstantBackoff(time.Millisecond)})).
+				Post(rsp)
+			if rsp.Error() != nil {
+				t.Fatalf("Post() error = %v", rsp.Error())
+			}
+			if want := []string{"hello", "hello"}; !reflect.DeepEqual(received, want) {
+				t.Errorf("Post() sent %q, want %q", received, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+const ContentTypeNDJson = "application/x-ndjson"
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// StreamUnmarshaler could be implemented by ContentTypeResolver to decode response body while reading it,
+// rather than reading the whole body into memory first, ctx is the context of the request.
+type StreamUnmarshaler interface {
+	UnmarshalStream(ctx context.Context, r io.Reader, v interface{}) error
+}
+
+// contentTypeNDJson decodes newline delimited JSON values one by one into:
+//
+//   - func(T) error or func(T), which is called for every value, decoding stops once it returns an error
+//   - chan T, every value is sent into it until the request is done, and it is not closed after decoding
+//   - *[]T, every value is appended to it
+type contentTypeNDJson struct{}
+
+func (c *contentTypeNDJson) Marshal(v interface{}) ([]byte, error) {
+	val := reflect.ValueOf(v)
+	if kind := val.Kind(); kind != reflect.Slice && kind != reflect.Array {
+		return json.Marshal(v)
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for i := 0; i < val.Len(); i++ {
+		if err := encoder.Encode(val.Index(i).Interface()); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *contentTypeNDJson) Unmarshal(data []byte, v interface{}) error {
+	return c.UnmarshalStream(context.Background(), bytes.NewReader(data), v)
+}
+
+func (c *contentTypeNDJson) UnmarshalStream(ctx context.Context, r io.Reader, v interface{}) error {
+	elemType, push, err := ndjsonSink(ctx, v)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(r)
+	for {
+		elem := reflect.New(elemType)
+		if err = decoder.Decode(elem.Interface()); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err = push(elem.Elem()); err != nil {
+			return err
+		}
+	}
+}
+
+// ndjsonSink returns the type of values and the function to deliver them into v.
+func ndjsonSink(ctx context.Context, v interface{}) (reflect.Type, func(reflect.Value) error, error) {
+	val := reflect.ValueOf(v)
+	if !val.IsValid() || (val.Kind() == reflect.Func || val.Kind() == reflect.Chan) && val.IsNil() {
+		return nil, nil, unsupportedNDJsonTarget(v)
+	}
+	typ := val.Type()
+	switch {
+	case typ.Kind() == reflect.Func && typ.NumIn() == 1 &&
+		(typ.NumOut() == 0 || typ.NumOut() == 1 && typ.Out(0) == errorType):
+		return typ.In(0), func(elem reflect.Value) error {
+			out := val.Call([]reflect.Value{elem})
+			if len(out) == 1 && !out[0].IsNil() {
+				return out[0].Interface().(error)
+			}
+			return nil
+		}, nil
+	case typ.Kind() == reflect.Chan && typ.ChanDir()&reflect.SendDir != 0:
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectSend, Chan: val},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		}
+		return typ.Elem(), func(elem reflect.Value) error {
+			cases[0].Send = elem
+			if chosen, _, _ := reflect.Select(cases); chosen == 1 {
+				return ctx.Err()
+			}
+			return nil
+		}, nil
+	case typ.Kind() == reflect.Ptr && typ.Elem().Kind() == reflect.Slice && !val.IsNil():
+		slice := val.Elem()
+		return typ.Elem().Elem(), func(elem reflect.Value) error {
+			slice.Set(reflect.Append(slice, elem))
+			return nil
+		}, nil
+	default:
+		return nil, nil, unsupportedNDJsonTarget(v)
+	}
+}
+
+func unsupportedNDJsonTarget(v interface{}) error {
+	return fmt.Errorf("unsupported %s target %T, func(T) error, chan T or *[]T required", ContentTypeNDJson, v)
+}
@@ -0,0 +1,189 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type logLine struct {
+	Seq int    `json:"seq"`
+	Msg string `json:"msg"`
+}
+
+func TestNDJsonStream(t *testing.T) {
+	received := make(chan int, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ContentTypeHeader, ContentTypeNDJson)
+		for i := 1; i <= 3; i++ {
+			_, _ = fmt.Fprintf(w, "{\"seq\": %d, \"msg\": \"line %d\"}\n", i, i)
+			w.(http.Flusher).Flush()
+			if r.URL.Path == "/incremental" {
+				// the next line is only written after the client decoded the current one
+				select {
+				case <-received:
+				case <-time.After(time.Second):
+					return
+				}
+			}
+		}
+		if r.URL.Path == "/malformed" {
+			_, _ = w.Write([]byte("{\"seq\": \n"))
+		}
+	}))
+	defer server.Close()
+
+	want := []logLine{{1, "line 1"}, {2, "line 2"}, {3, "line 3"}}
+	tests := []struct {
+		name    string
+		path    string
+		run     func(req *Request) ([]logLine, error)
+		want    []logLine
+		wantErr bool
+	}{
+		{
+			name: "callback",
+			path: "incremental",
+			run: func(req *Request) ([]logLine, error) {
+				var lines []logLine
+				_, err := GetNDJSON(req, func(line logLine) error {
+					lines = append(lines, line)
+					received <- line.Seq
+					return nil
+				})
+				return lines, err
+			},
+			want: want,
+		},
+		{
+			name: "callback stops",
+			run: func(req *Request) ([]logLine, error) {
+				var lines []logLine
+				_, err := GetNDJSON(req, func(line logLine) error {
+					lines = append(lines, line)
+					return errors.New("enough")
+				})
+				return lines, err
+			},
+			want:    want[:1],
+			wantErr: true,
+		},
+		{
+			name: "channel",
+			run: func(req *Request) ([]logLine, error) {
+				ch := make(chan logLine)
+				rsp := &DefaultResponse{Data: ch}
+				go func() {
+					req.Accept(ContentTypeNDJson).Get(rsp)
+					close(ch)
+				}()
+				var lines []logLine
+				for line := range ch {
+					lines = append(lines, line)
+				}
+				return lines, rsp.Error()
+			},
+			want: want,
+		},
+		{
+			name: "channel abandoned",
+			run: func(req *Request) ([]logLine, error) {
+				rsp := &DefaultResponse{Data: make(chan logLine)}
+				done := make(chan struct{})
+				go func() {
+					req.WithTimeout(100 * time.Millisecond).Accept(ContentTypeNDJson).Get(rsp)
+					close(done)
+				}()
+				select {
+				case <-done:
+				case <-time.After(time.Second):
+					return nil, nil
+				}
+				return nil, rsp.Error()
+			},
+			wantErr: true,
+		},
+		{
+			name: "slice",
+			run: func(req *Request) ([]logLine, error) {
+				var lines []logLine
+				rsp := &DefaultResponse{Data: &lines}
+				req.Accept(ContentTypeNDJson).Get(rsp)
+				return lines, rsp.Error()
+			},
+			want: want,
+		},
+		{
+			name: "malformed",
+			path: "malformed",
+			run: func(req *Request) ([]logLine, error) {
+				var lines []logLine
+				rsp := &DefaultResponse{Data: &lines}
+				req.Accept(ContentTypeNDJson).Get(rsp)
+				return lines, rsp.Error()
+			},
+			want:    want,
+			wantErr: true,
+		},
+		{
+			name: "unsupported target",
+			run: func(req *Request) ([]logLine, error) {
+				rsp := &DefaultResponse{Data: &logLine{}}
+				req.Accept(ContentTypeNDJson).Get(rsp)
+				return nil, rsp.Error()
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.run(Req().WithHostName(server.URL).WithPath(tt.path))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NDJSON error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NDJSON got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_contentTypeNDJson_Unmarshal(t *testing.T) {
+	var lines []logLine
+	var nilFunc func(logLine) error
+	var nilChan chan logLine
+	tests := []struct {
+		name    string
+		v       interface{}
+		wantErr bool
+	}{
+		{name: "slice", v: &lines},
+		{name: "nil", v: nil, wantErr: true},
+		{name: "nil func", v: nilFunc, wantErr: true},
+		{name: "nil chan", v: nilChan, wantErr: true},
+		{name: "nil slice pointer", v: (*[]logLine)(nil), wantErr: true},
+		{name: "struct", v: &logLine{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&contentTypeNDJson{}).Unmarshal([]byte("{\"seq\":1}\n"), tt.v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_contentTypeNDJson_Marshal(t *testing.T) {
+	got, err := (&contentTypeNDJson{}).Marshal([]logLine{{1, "a"}, {2, "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"seq\":1,\"msg\":\"a\"}\n{\"seq\":2,\"msg\":\"b\"}\n"; string(got) != want {
+		t.Errorf("Marshal() got = %q, want %q", got, want)
+	}
+}
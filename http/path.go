@@ -0,0 +1,86 @@
+package http
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const pathTagName = "path"
+
+// PathParam sets the value of `{key}` in path template of current Request. The value is escaped as one path segment,
+// so that it never changes the structure of the path, ie: a slash in value is sent as %2F.
+func (r *Request) PathParam(key string, value interface{}) *Request {
+	if r.pathParams == nil {
+		r.pathParams = make(map[string]interface{})
+	}
+	r.pathParams[key] = value
+	return r
+}
+
+// PathParams sets the values of path template from a struct with `path` tags or a map.
+// The tags follow the same attributes as `query` tags, ie: `path:"id,required"`.
+func (r *Request) PathParams(params interface{}) *Request {
+	m, err := formToMap(params, pathTagName)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	for key, value := range m {
+		r.PathParam(key, value)
+	}
+	return r
+}
+
+// renderPath replaces the `{key}` placeholders in template with escaped params. Both the unescaped path and the
+// escaped one are returned, so that url.URL keeps the escaped slashes.
+func renderPath(template string, params map[string]interface{}) (string, string, error) {
+	if !strings.Contains(template, "{") {
+		return template, "", nil
+	}
+	var path, rawPath strings.Builder
+	for rest := template; rest != ""; {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			path.WriteString(rest)
+			rawPath.WriteString(escapePath(rest))
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return "", "", fmt.Errorf("unclosed path parameter in %s", template)
+		}
+		end += start
+		key := rest[start+1 : end]
+		value, ok := params[key]
+		if !ok {
+			return "", "", fmt.Errorf("missing path parameter `%s` of %s", key, template)
+		}
+		segment := fmt.Sprintf("%v", value)
+		if segment == "" || segment == "." || segment == ".." {
+			// dot segments are not escaped, but they would be resolved against the path by server
+			return "", "", fmt.Errorf("invalid path parameter `%s` of %s: %q", key, template, segment)
+		}
+		path.WriteString(rest[:start])
+		path.WriteString(segment)
+		rawPath.WriteString(escapePath(rest[:start]))
+		rawPath.WriteString(url.PathEscape(segment))
+		rest = rest[end+1:]
+	}
+	return path.String(), rawPath.String(), nil
+}
+
+// escapePath escapes the literal part of path template, its slashes are kept.
+func escapePath(literal string) string {
+	return (&url.URL{Path: literal}).EscapedPath()
+}
+
+// renderedPath returns the path of current Request with path parameters filled, the template is returned if it could
+// not be rendered.
+func (r *Request) renderedPath() string {
+	path, _, err := renderPath(r.Path, r.pathParams)
+	if err != nil {
+		return r.Path
+	}
+	return path
+}
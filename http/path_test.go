@@ -0,0 +1,134 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestPathParam(t *testing.T) {
+	type orderPath struct {
+		ID      int    `path:"id"`
+		OrderID string `path:"orderId,required"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`"` + r.URL.EscapedPath() + `"`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		path         string
+		build        func(req *Request) *Request
+		want         string
+		wantTemplate string
+		wantErr      bool
+	}{
+		{
+			name:         "literal",
+			path:         "/users",
+			build:        func(req *Request) *Request { return req },
+			want:         "/users",
+			wantTemplate: "users",
+		},
+		{
+			name: "path params",
+			path: "/users/{id}/orders/{orderId}",
+			build: func(req *Request) *Request {
+				return req.PathParam("id", 42).PathParam("orderId", "a1")
+			},
+			want:         "/users/42/orders/a1",
+			wantTemplate: "users/{id}/orders/{orderId}",
+		},
+		{
+			name: "escaped",
+			path: "/users/{id}",
+			build: func(req *Request) *Request {
+				return req.PathParam("id", "../admin/x y?z")
+			},
+			want:         "/users/..%2Fadmin%2Fx%20y%3Fz",
+			wantTemplate: "users/{id}",
+		},
+		{
+			name: "escaped literal",
+			path: "/files/my docs/{id}",
+			build: func(req *Request) *Request {
+				return req.PathParam("id", "a/../b")
+			},
+			want:         "/files/my%20docs/a%2F..%2Fb",
+			wantTemplate: "files/my docs/{id}",
+		},
+		{
+			name: "struct tags",
+			path: "/users/{id}/orders/{orderId}",
+			build: func(req *Request) *Request {
+				return req.PathParams(&orderPath{ID: 7, OrderID: "b/2"})
+			},
+			want:         "/users/7/orders/b%2F2",
+			wantTemplate: "users/{id}/orders/{orderId}",
+		},
+		{
+			name: "required struct field",
+			path: "/users/{id}/orders/{orderId}",
+			build: func(req *Request) *Request {
+				return req.PathParams(orderPath{ID: 7})
+			},
+			wantErr: true,
+		},
+		{
+			name:    "missing param",
+			path:    "/users/{id}",
+			build:   func(req *Request) *Request { return req },
+			wantErr: true,
+		},
+		{
+			name: "empty param",
+			path: "/users/{id}",
+			build: func(req *Request) *Request {
+				return req.PathParam("id", "")
+			},
+			wantErr: true,
+		},
+		{
+			name: "dot segment",
+			path: "/users/{id}/orders",
+			build: func(req *Request) *Request {
+				return req.PathParam("id", "..")
+			},
+			wantErr: true,
+		},
+		{
+			name: "unclosed param",
+			path: "/users/{id",
+			build: func(req *Request) *Request {
+				return req.PathParam("id", 1)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params LogFormatterParams
+			var got string
+			rsp := &DefaultResponse{Data: &got}
+			tt.build(Req().WithHostName(server.URL).WithPath(tt.path)).
+				Use(LoggerWithFormatter(func(param LogFormatterParams) string {
+					params = param
+					return ""
+				})).
+				Get(rsp)
+			if (rsp.Error() != nil) != tt.wantErr {
+				t.Fatalf("Get() error = %v, wantErr %v", rsp.Error(), tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Get() path = %s, want %s", got, tt.want)
+			}
+			if params.PathTemplate != tt.wantTemplate {
+				t.Errorf("LogFormatterParams.PathTemplate = %s, want %s", params.PathTemplate, tt.wantTemplate)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Archer1A/go-kits/utils/mask"
+)
+
+const redacted = "[REDACTED]"
+
+// defaultMaxBodySize is the max bytes of bodies logged if MaxBodySize is not set.
+const defaultMaxBodySize = 4 << 10
+
+// Mask masks a sensitive value in logs.
+type Mask func(string) string
+
+var (
+	// MaskFull replaces the whole value.
+	MaskFull Mask = func(string) string { return redacted }
+	// MaskPhone keeps the first 3 and last 4 digits of phone number.
+	MaskPhone Mask = mask.Phone
+	// MaskEmail keeps the first and last characters of the local part of email.
+	MaskEmail Mask = mask.Email
+	// MaskUsername keeps the first and last characters of username.
+	MaskUsername Mask = mask.Username
+)
+
+// DefaultRedactedHeaders are always replaced in logs, in addition to the headers of Redaction.
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// Redaction declares the sensitive values masked in logs, a nil Mask replaces the whole value.
+type Redaction struct {
+	// Headers are request and response header names, which are case-insensitive.
+	Headers map[string]Mask
+	// Queries are query keys.
+	Queries map[string]Mask
+	// Fields are the dotted paths of fields in JSON bodies, ie: user.phone. Arrays are walked through, so items.email
+	// masks the email of every item, and * matches any key. Keys of form bodies are matched as they are.
+	Fields map[string]Mask
+}
+
+func (r Redaction) mask(m Mask, value string) string {
+	if m == nil {
+		return MaskFull(value)
+	}
+	return m(value)
+}
+
+func (r Redaction) header(header http.Header) http.Header {
+	if header == nil {
+		return nil
+	}
+	masks := make(map[string]Mask, len(DefaultRedactedHeaders)+len(r.Headers))
+	for _, name := range DefaultRedactedHeaders {
+		masks[http.CanonicalHeaderKey(name)] = MaskFull
+	}
+	for name, m := range r.Headers {
+		masks[http.CanonicalHeaderKey(name)] = m
+	}
+	out := make(http.Header, len(header))
+	for name, values := range header {
+		m, sensitive := masks[http.CanonicalHeaderKey(name)]
+		masked := make([]string, len(values))
+		for i, value := range values {
+			if sensitive {
+				value = r.mask(m, value)
+			}
+			masked[i] = value
+		}
+		out[name] = masked
+	}
+	return out
+}
+
+func (r Redaction) query(query interface{}) string {
+	if query == nil {
+		return ""
+	}
+	m, err := formToMap(query, queryTagName)
+	if err != nil {
+		return ""
+	}
+	values := url.Values{}
+	for key, value := range m {
+		values[key] = formValues(value)
+	}
+	return r.values(values, r.Queries).Encode()
+}
+
+// values returns a copy of values with the sensitive keys masked.
+func (r Redaction) values(values url.Values, masks map[string]Mask) url.Values {
+	out := make(url.Values, len(values))
+	for key, vs := range values {
+		masked := append([]string(nil), vs...)
+		if m, sensitive := masks[key]; sensitive {
+			for i := range masked {
+				masked[i] = r.mask(m, masked[i])
+			}
+		}
+		out[key] = masked
+	}
+	return out
+}
+
+// body returns the redacted body for logs. Only complete JSON and form bodies are logged, others are summarized
+// by their sizes, since it is unknown whether they carry sensitive values.
+func (r Redaction) body(capture *captureBuffer, contentType string) string {
+	if capture == nil || capture.total == 0 {
+		return ""
+	}
+	omitted := fmt.Sprintf("[%d bytes omitted]", capture.total)
+	if capture.truncated() {
+		return omitted
+	}
+	if masked, ok := r.maskBody(capture.buf.Bytes(), contentType); ok {
+		return string(masked)
+	}
+	return omitted
+}
+
+// maskBody masks the Fields of JSON and form bodies, false is returned if the body is neither of them or malformed.
+func (r Redaction) maskBody(data []byte, contentType string) ([]byte, bool) {
+	switch t := mediaType(contentType); {
+	case t == ContentTypeJson || strings.HasSuffix(t, "+json"):
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		var v interface{}
+		if err := decoder.Decode(&v); err != nil {
+			return nil, false
+		}
+		for path, m := range r.Fields {
+			v = r.maskField(v, strings.Split(path, "."), m)
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, false
+		}
+		return b, true
+	case t == ContentTypeFrom:
+		values, err := url.ParseQuery(string(data))
+		if err != nil {
+			return nil, false
+		}
+		return []byte(r.values(values, r.Fields).Encode()), true
+	default:
+		return nil, false
+	}
+}
+
+func (r Redaction) maskField(v interface{}, path []string, m Mask) interface{} {
+	switch value := v.(type) {
+	case []interface{}:
+		for i := range value {
+			value[i] = r.maskField(value[i], path, m)
+		}
+		return value
+	case map[string]interface{}:
+		if len(path) == 0 {
+			return r.mask(nil, "")
+		}
+		for key := range value {
+			if path[0] == "*" || path[0] == key {
+				value[key] = r.maskField(value[key], path[1:], m)
+			}
+		}
+		return value
+	case nil:
+		return nil
+	default:
+		if len(path) > 0 {
+			return v
+		}
+		return r.mask(m, fmt.Sprintf("%v", value))
+	}
+}
+
+// captureBuffer keeps the first limit bytes written, and counts all of them.
+type captureBuffer struct {
+	limit int
+	buf   bytes.Buffer
+	total int64
+}
+
+func newCaptureBuffer(limit int) *captureBuffer {
+	return &captureBuffer{limit: limit}
+}
+
+func (c *captureBuffer) Write(p []byte) (int, error) {
+	c.total += int64(len(p))
+	if remaining := c.limit - c.buf.Len(); remaining > 0 {
+		if len(p) > remaining {
+			c.buf.Write(p[:remaining])
+		} else {
+			c.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (c *captureBuffer) truncated() bool {
+	return c.total > int64(c.buf.Len())
+}
+
+// teeBody copies the request body into capture while it is sent.
+type teeBody struct {
+	body    io.ReadCloser
+	capture io.Writer
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.body.Read(p)
+	if n > 0 {
+		_, _ = t.capture.Write(p[:n])
+	}
+	return n, err
+}
+
+func (t *teeBody) Close() error {
+	return t.body.Close()
+}
+
+// logDetails are the optional details of Request in logs, they are redacted before being put into LogFormatterParams.
+type logDetails struct {
+	query       bool
+	headers     bool
+	body        bool
+	maxBodySize int
+	redaction   Redaction
+}
+
+func newLogDetails(query, headers, body bool, maxBodySize int, redaction Redaction) logDetails {
+	if maxBodySize <= 0 {
+		maxBodySize = defaultMaxBodySize
+	}
+	return logDetails{query: query, headers: headers, body: body, maxBodySize: maxBodySize, redaction: redaction}
+}
+
+// prepare asks doHttpReq to capture bodies, it should be called before the pending handlers.
+func (d logDetails) prepare(ctx *Context) {
+	if d.body && ctx.captureBody < d.maxBodySize {
+		ctx.captureBody = d.maxBodySize
+	}
+}
+
+func (d logDetails) fill(ctx *Context, param *LogFormatterParams) {
+	req, raw := ctx.Request, ctx.Response.HttpResponse()
+	if d.query {
+		param.Query = d.redaction.query(req.Query)
+	}
+	if d.headers {
+		header := make(http.Header, len(req.Headers))
+		for key, value := range req.Headers {
+			header.Set(key, value)
+		}
+		param.RequestHeader = d.redaction.header(header)
+		if raw != nil {
+			param.ResponseHeader = d.redaction.header(raw.Header)
+		}
+	}
+	if d.body {
+		contentType := req.Headers[ContentTypeHeader]
+		if contentType == "" {
+			contentType = ContentTypeJson
+		}
+		param.RequestBody = d.redaction.body(ctx.requestBody, contentType)
+		if raw != nil {
+			param.ResponseBody = d.redaction.body(ctx.responseBody, raw.Header.Get(ContentTypeHeader))
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package http
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedactionBody(t *testing.T) {
+	redaction := Redaction{Fields: map[string]Mask{
+		"user.phone":  MaskPhone,
+		"items.email": MaskEmail,
+		"token":       nil,
+		"*.secret":    nil,
+	}}
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		limit       int
+		want        string
+	}{
+		{
+			name:        "nested field",
+			body:        `{"user":{"name":"alice","phone":"13812345678"}}`,
+			contentType: ContentTypeJson,
+			want:        `{"user":{"name":"alice","phone":"138****5678"}}`,
+		},
+		{
+			name:        "array field",
+			body:        `{"items":[{"email":"alice@example.com"},{"id":2}]}`,
+			contentType: ContentTypeJson,
+			want:        `{"items":[{"email":"` + MaskEmail("alice@example.com") + `"},{"id":2}]}`,
+		},
+		{
+			name:        "wildcard and object",
+			body:        `{"token":{"value":"abc"},"db":{"secret":123}}`,
+			contentType: "application/problem+json; charset=utf-8",
+			want:        `{"db":{"secret":"[REDACTED]"},"token":"[REDACTED]"}`,
+		},
+		{
+			name:        "form",
+			body:        "name=alice&token=abc",
+			contentType: ContentTypeFrom,
+			want:        "name=alice&token=%5BREDACTED%5D",
+		},
+		{
+			name:        "truncated",
+			body:        `{"token":"abcdefghijklmnopqrstuvwxyz"}`,
+			contentType: ContentTypeJson,
+			limit:       8,
+			want:        "[38 bytes omitted]",
+		},
+		{
+			name:        "not JSON nor form",
+			body:        "token=abc",
+			contentType: "text/plain",
+			want:        "[9 bytes omitted]",
+		},
+		{
+			name:        "malformed JSON",
+			body:        `{"token":`,
+			contentType: ContentTypeJson,
+			want:        "[9 bytes omitted]",
+		},
+		{
+			name:        "empty",
+			contentType: ContentTypeJson,
+			want:        "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit := tt.limit
+			if limit == 0 {
+				limit = defaultMaxBodySize
+			}
+			capture := newCaptureBuffer(limit)
+			_, _ = capture.Write([]byte(tt.body))
+			if got := redaction.body(capture, tt.contentType); got != tt.want {
+				t.Errorf("body() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactionHeader(t *testing.T) {
+	redaction := Redaction{Headers: map[string]Mask{"x-session": MaskFull, "X-User": MaskUsername}}
+	got := redaction.header(http.Header{
+		"Authorization": {"Bearer abc"},
+		"X-Session":     {"s1", "s2"},
+		"X-User":        {"alice"},
+		"Accept":        {ContentTypeJson},
+	})
+	want := http.Header{
+		"Authorization": {redacted},
+		"X-Session":     {redacted, redacted},
+		"X-User":        {"a***e"},
+		"Accept":        {ContentTypeJson},
+	}
+	for name, values := range want {
+		if strings.Join(got[name], ",") != strings.Join(values, ",") {
+			t.Errorf("header() %s = %v, want %v", name, got[name], values)
+		}
+	}
+}
+
+type redactionQuery struct {
+	Phone string `query:"phone"`
+	Page  int    `query:"page"`
+}
+
+func TestLoggerRedaction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ContentTypeHeader, ContentTypeJson)
+		w.Header().Set("Set-Cookie", "session=abc")
+		_, _ = w.Write([]byte(`{"data":{"token":"t0ken","id":1}}`))
+	}))
+	defer server.Close()
+
+	config := LoggerConfig{
+		LogQuery:   true,
+		LogHeaders: true,
+		LogBody:    true,
+		Redaction: Redaction{
+			Queries: map[string]Mask{"phone": MaskPhone},
+			Fields:  map[string]Mask{"password": nil, "data.token": nil},
+		},
+	}
+	send := func(logger HandlerFunc) {
+		rsp := &DefaultResponse{}
+		Req().WithHostName(server.URL).WithPath("/login").
+			WithHeaders(map[string]string{"Authorization": "Bearer abc"}).
+			WithQueries(redactionQuery{Phone: "13812345678", Page: 1}).
+			WithBody(map[string]string{"user": "alice", "password": "secret"}).
+			Use(logger).Post(rsp)
+		if rsp.Error() != nil {
+			t.Fatalf("Post() error = %v", rsp.Error())
+		}
+	}
+
+	t.Run("formatter", func(t *testing.T) {
+		var buf bytes.Buffer
+		config := config
+		config.Output = &buf
+		send(LoggerWithConfig(config))
+
+		line := buf.String()
+		for _, want := range []string{
+			"/login?page=1&phone=138%2A%2A%2A%2A5678",
+			"Authorization:[[REDACTED]]",
+			`request body: {"password":"[REDACTED]","user":"alice"}`,
+			"Set-Cookie:[[REDACTED]]",
+			`response body: {"data":{"id":1,"token":"[REDACTED]"}}`,
+		} {
+			if !strings.Contains(line, want) {
+				t.Errorf("LoggerWithConfig() line %s does not contain %s", line, want)
+			}
+		}
+		for _, secret := range []string{"Bearer abc", "secret", "t0ken", "13812345678", "session=abc"} {
+			if strings.Contains(line, secret) {
+				t.Errorf("LoggerWithConfig() line %s leaks %s", line, secret)
+			}
+		}
+	})
+
+	t.Run("slog", func(t *testing.T) {
+		var buf bytes.Buffer
+		config := config
+		config.Logger = slog.New(slog.NewJSONHandler(&buf, nil))
+		send(LoggerWithConfig(config))
+
+		records := decodeRecords(t, &buf)
+		if len(records) != 1 {
+			t.Fatalf("SlogLogger() logged %d records, want 1", len(records))
+		}
+		record := records[0]
+		if record["query"] != "page=1&phone=138%2A%2A%2A%2A5678" ||
+			record["request_body"] != `{"password":"[REDACTED]","user":"alice"}` ||
+			record["response_body"] != `{"data":{"id":1,"token":"[REDACTED]"}}` {
+			t.Errorf("SlogLogger() record = %v", record)
+		}
+		headers, _ := record["request_headers"].(map[string]interface{})
+		if auth, _ := headers["Authorization"].([]interface{}); len(auth) != 1 || auth[0] != redacted {
+			t.Errorf("SlogLogger() request_headers = %v", record["request_headers"])
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		var buf bytes.Buffer
+		send(LoggerWithConfig(LoggerConfig{Output: &buf}))
+		if line := buf.String(); strings.Count(line, "|") != 3 || strings.Contains(line, "?") {
+			t.Errorf("LoggerWithConfig() line = %s, want no details", line)
+		}
+	})
+}
+
+func TestLoggerRedactionStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/problem" {
+			w.Header().Set(ContentTypeHeader, ContentTypeProblemJson)
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"title":"Forbidden","token":"secret-abc"}`))
+			return
+		}
+		w.Header().Set(ContentTypeHeader, ContentTypeJson)
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"token":"secret-abc","message":"boom"}`))
+	}))
+	defer server.Close()
+
+	redaction := Redaction{Fields: map[string]Mask{"token": nil}}
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "raw body", path: "/raw", want: "unexpected status 500 Internal Server Error"},
+		{name: "problem", path: "/problem", want: "unexpected status 403 Forbidden: Forbidden"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var line, records bytes.Buffer
+			exporter := &InMemoryExporter{}
+			rsp := &DefaultResponse{}
+			Req().WithHostName(server.URL).WithPath(tt.path).Use(
+				LoggerWithConfig(LoggerConfig{Output: &line, LogBody: true, Redaction: redaction}),
+				SlogLogger(slog.New(slog.NewJSONHandler(&records, nil)), SlogOptions{LogBody: true, Redaction: redaction}),
+				TracingWithConfig(TracingConfig{Exporter: exporter}),
+			).Get(rsp)
+			if rsp.Error() == nil {
+				t.Fatalf("Get() error = nil, want StatusError")
+			}
+			spans := exporter.Spans()
+			if len(spans) != 1 {
+				t.Fatalf("Tracing() exported %d spans, want 1", len(spans))
+			}
+			for name, output := range map[string]string{
+				"LoggerWithConfig": line.String(),
+				"SlogLogger":       records.String(),
+				"Tracing":          spans[0].StatusMessage,
+			} {
+				if strings.Contains(output, "secret-abc") || !strings.Contains(output, tt.want) {
+					t.Errorf("%s logged %s, want %s without secret", name, output, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -17,23 +18,23 @@ type Request struct {
 	Body        interface{}
 	Query       interface{}
 	Headers     map[string]string
+	Resolver    Resolver
+	// StatusPolicy decides which responses are errors, DefaultStatusPolicy is used if it is nil.
+	StatusPolicy *StatusPolicy
+	// Compression compresses request body, body is sent as it is if it is nil.
+	Compression *Compression
+	pathParams  map[string]interface{}
+	balancer    *loadBalancer
+	client      *Client
 	ctx         *Context
 	Timeout     *time.Duration
 	err         error
 	Secure      bool
 }
 
-// Req returns a new Request instance.
+// Req returns a new Request instance from DefaultClient.
 func Req() *Request {
-	ctx := &Context{Context: context.Background()}
-	req := &Request{
-		Timeout: globalTimeout, // timeout can be override later by calling WithTimeout() in Request
-		Headers: globalHeaders,
-	}
-	ctx.Request = req
-	req.ctx = ctx
-	ctx.handlers = append(ctx.handlers, globalHandlers...)
-	return req
+	return DefaultClient.Req()
 }
 
 // Use adds middlewares to current Request.
@@ -61,6 +62,19 @@ func (r *Request) Port(port uint) *Request {
 	return r
 }
 
+// WithResolver sets the Resolver which turns service name and port into endpoints when current Request is sent.
+// Service name is used as host name directly if no Resolver is set.
+func (r *Request) WithResolver(resolver Resolver) *Request {
+	r.Resolver = resolver
+	return r
+}
+
+// WithStatusPolicy sets the StatusPolicy of current Request.
+func (r *Request) WithStatusPolicy(policy *StatusPolicy) *Request {
+	r.StatusPolicy = policy
+	return r
+}
+
 func (r *Request) WithHostName(hostName string) *Request {
 	r.HostName = hostName
 	return r
@@ -71,7 +85,8 @@ func (r *Request) HostAndPort(serviceName string, port uint) *Request {
 	return r.Host(serviceName).Port(port)
 }
 
-// WithPath sets request path of current Request.
+// WithPath sets request path of current Request. The path could be a template with `{key}` placeholders,
+// ie: /users/{id}/orders/{orderId}, which are filled by PathParam or PathParams when the Request is sent.
 func (r *Request) WithPath(path string) *Request {
 	r.Path = strings.TrimPrefix(path, "/")
 	return r
@@ -91,26 +106,33 @@ func (r *Request) WithHeaders(headers map[string]string) *Request {
 	return r
 }
 
+// ContentType sets Content-Type header of current Request, the request body is marshaled by the ContentTypeResolver
+// registered for it.
 func (r *Request) ContentType(contentType string) *Request {
 	return r.contentHandle(contentType, ContentTypeHeader)
 }
 
+// Accept sets Accept header of current Request, the response body is unmarshaled by the ContentTypeResolver
+// registered for it.
 func (r *Request) Accept(contentType string) *Request {
 	return r.contentHandle(contentType, AcceptTypeHeader)
 }
 
 func (r *Request) contentHandle(contentType string, headerKey string) *Request {
-	switch contentType {
-	case ContentTypeJson:
-		fallthrough
-	case ContentTypeFrom:
-		if r.Headers == nil {
-			r.Headers = make(map[string]string)
-		}
-		r.Headers[headerKey] = contentType
-	default:
+	var ok bool
+	if headerKey == AcceptTypeHeader {
+		_, ok = acceptResolver(r.client, contentType)
+	} else {
+		_, ok = r.client.contentResolver(mediaType(contentType))
+	}
+	if !ok {
 		r.err = fmt.Errorf("unsupported %s %s", headerKey, contentType)
+		return r
+	}
+	if r.Headers == nil {
+		r.Headers = make(map[string]string)
 	}
+	r.Headers[headerKey] = contentType
 	return r
 }
 
@@ -127,7 +149,8 @@ func (r *Request) WithBody(body interface{}) *Request {
 }
 
 // WithTimeout sets timeout for the whole processing chain of current Request. Note that this will override global timeout.
-// Once timeout be reached, the HTTP request and all middlewares will be canceled from executing.
+// The timeout is derived from the context set by WithContext. Once timeout be reached, the HTTP request is canceled,
+// middlewares should watch Context.Done to stop, and context.DeadlineExceeded is saved into Response.
 func (r *Request) WithTimeout(d time.Duration) *Request {
 	r.Timeout = &d
 	return r
@@ -138,6 +161,7 @@ func (r *Request) WithSecure(secure bool) *Request {
 	return r
 }
 
+// WithContext sets the context that the processing chain of current Request runs with.
 func (r *Request) WithContext(ctx context.Context) *Request {
 	r.ctx.Context = ctx
 	return r
@@ -187,26 +211,40 @@ func (r *Request) do(rsp Response) {
 		rsp.ErrorSave(r.err)
 		return
 	}
+	// malformed Requests never reach middlewares, so they are neither retried nor counted by circuit breaker
+	if err := validateRequest(r); err != nil {
+		rsp.ErrorSave(err)
+		return
+	}
+	// the chain is rebuilt every time, so that current Request could be executed again, ie: reconnecting SSE
+	middlewares, parent := r.ctx.handlers, r.ctx.Context
+	defer func() {
+		r.ctx.handlers, r.ctx.Context = middlewares, parent
+	}()
 	r.ctx.Response = rsp
-	r.ctx.handlers = append(r.ctx.handlers, doHttpReq)
-	if r.Timeout != nil {
-		var cancelFn context.CancelFunc
-		r.ctx.Context, cancelFn = context.WithTimeout(context.TODO(), *r.Timeout)
-		timer := time.NewTimer(*r.Timeout)
-		done := make(chan struct{})
-		go func() {
-			r.ctx.handlers[0](r.ctx)
-			close(done)
-		}()
-		select {
-		case <-timer.C:
-			cancelFn()
-		case <-done:
-			cancelFn()
-			timer.Stop()
-		}
-	} else {
+	r.ctx.handlers = append(middlewares[:len(middlewares):len(middlewares)], doHttpReq)
+	r.ctx.index, r.ctx.attempt = 0, 0
+	if r.ctx.Context == nil {
+		r.ctx.Context = context.Background()
+	}
+	if r.Timeout == nil {
 		r.ctx.handlers[0](r.ctx)
+		return
+	}
+
+	// the chain runs in the calling goroutine, so it has always observed the cancellation when do returns
+	timeoutCtx, cancelFn := context.WithTimeout(r.ctx.Context, *r.Timeout)
+	r.ctx.cancel = cancelFn
+	defer func() {
+		// cancel is taken away if the response body is streamed, and it will be called when the body is closed
+		if r.ctx.cancel != nil {
+			r.ctx.cancel()
+		}
+	}()
+	r.ctx.Context = timeoutCtx
+	r.ctx.handlers[0](r.ctx)
+	if errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) && !errors.Is(rsp.Error(), context.DeadlineExceeded) {
+		rsp.ErrorSave(context.DeadlineExceeded)
 	}
 }
 
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	tests := []struct {
+		name    string
+		req     func(base *Request) *Request
+		wantErr error
+	}{
+		{
+			name: "http request timeout",
+			req: func(base *Request) *Request {
+				return base.WithHostName(server.URL).WithTimeout(50 * time.Millisecond)
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "middleware observes timeout",
+			req: func(base *Request) *Request {
+				return base.WithHostName(server.URL).WithTimeout(50 * time.Millisecond).Use(func(ctx *Context) {
+					<-ctx.Done()
+					ctx.Abort()
+				})
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "middleware ignores timeout",
+			req: func(base *Request) *Request {
+				return base.WithHostName(server.URL).WithTimeout(10 * time.Millisecond).Use(func(ctx *Context) {
+					time.Sleep(50 * time.Millisecond)
+					ctx.Abort()
+				})
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "derived from caller context",
+			req: func(base *Request) *Request {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(20*time.Millisecond, cancel)
+				return base.WithHostName(server.URL).WithTimeout(time.Minute).WithContext(ctx)
+			},
+			wantErr: context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			running := atomic.Bool{}
+			client := NewClient().Use(func(ctx *Context) {
+				running.Store(true)
+				defer running.Store(false)
+				ctx.Next()
+			})
+			req := tt.req(client.Req())
+			rsp := &DefaultResponse{}
+			req.Get(rsp)
+			if !errors.Is(rsp.Error(), tt.wantErr) {
+				t.Errorf("Request.Get() error = %v, want %v", rsp.Error(), tt.wantErr)
+			}
+			if running.Load() {
+				t.Error("Request.Get() returned before the chain finished")
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Endpoint is a concrete address of a remote service.
+// Port 0 means the port is not decided by resolver, and the port of Request will be used.
+type Endpoint struct {
+	Host string
+	Port uint
+}
+
+func (e Endpoint) String() string {
+	if e.Port == 0 {
+		return e.Host
+	}
+	return net.JoinHostPort(e.Host, strconv.Itoa(int(e.Port)))
+}
+
+// ParseEndpoint parses an endpoint in form of host or host:port.
+func ParseEndpoint(s string) (Endpoint, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Endpoint{}, fmt.Errorf("empty endpoint")
+	}
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		// no port in address
+		return Endpoint{Host: strings.Trim(s, "[]")}, nil
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return Endpoint{}, fmt.Errorf("invalid port of endpoint %s", s)
+	}
+	return Endpoint{Host: host, Port: uint(p)}, nil
+}
+
+// Resolver turns service name and port of Request into concrete endpoints when the Request is sent.
+type Resolver interface {
+	Resolve(ctx context.Context, serviceName string, port uint) ([]Endpoint, error)
+}
+
+type passthroughResolver struct{}
+
+// Resolve uses the service name as host name directly.
+func (passthroughResolver) Resolve(_ context.Context, serviceName string, port uint) ([]Endpoint, error) {
+	return []Endpoint{{Host: serviceName, Port: port}}, nil
+}
+
+// StaticResolver resolves service names from a fixed map.
+type StaticResolver map[string][]Endpoint
+
+func (s StaticResolver) Resolve(_ context.Context, serviceName string, port uint) ([]Endpoint, error) {
+	endpoints, ok := s[serviceName]
+	if !ok || len(endpoints) == 0 {
+		return nil, fmt.Errorf("no endpoint found for service %s", serviceName)
+	}
+	return withPort(endpoints, port), nil
+}
+
+// withPort returns a copy of endpoints that fills the missing ports with the given port.
+func withPort(endpoints []Endpoint, port uint) []Endpoint {
+	result := make([]Endpoint, len(endpoints))
+	for i, endpoint := range endpoints {
+		if endpoint.Port == 0 {
+			endpoint.Port = port
+		}
+		result[i] = endpoint
+	}
+	return result
+}
+
+// DNSSRVResolver resolves service names by DNS SRV records, ie: _Service._Proto.serviceName.
+// If Service and Proto are both empty, serviceName is looked up directly.
+// Port of Request is ignored because SRV records carry their own ports.
+type DNSSRVResolver struct {
+	Service  string
+	Proto    string
+	Resolver *net.Resolver
+}
+
+func (d *DNSSRVResolver) Resolve(ctx context.Context, serviceName string, _ uint) ([]Endpoint, error) {
+	resolver := d.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	_, records, err := resolver.LookupSRV(ctx, d.Service, d.Proto, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no SRV record found for service %s", serviceName)
+	}
+	// records are sorted by priority, only the ones with the highest priority are returned
+	var endpoints []Endpoint
+	for _, record := range records {
+		if record.Priority != records[0].Priority {
+			break
+		}
+		endpoints = append(endpoints, Endpoint{Host: strings.TrimSuffix(record.Target, "."), Port: uint(record.Port)})
+	}
+	return endpoints, nil
+}
+
+// FileResolver resolves service names from a registry file, which will be reloaded once it is modified.
+//
+// The registry maps service names to lists of host or host:port. Files ending with .yaml or .yml are parsed as
+// YAML, only block mappings of block sequences are supported:
+//
+//	billing:
+//	  - 10.0.0.1:8080
+//	  - 10.0.0.2:8080
+//
+// Other files are parsed as JSON:
+//
+//	{"billing": ["10.0.0.1:8080", "10.0.0.2:8080"]}
+type FileResolver struct {
+	path     string
+	mu       sync.RWMutex
+	registry StaticResolver
+	modTime  time.Time
+	stop     chan struct{}
+	once     sync.Once
+}
+
+// NewFileResolver loads the registry file and watches it every interval.
+// Call Close to stop watching.
+func NewFileResolver(path string, interval time.Duration) (*FileResolver, error) {
+	f := &FileResolver{path: path, stop: make(chan struct{})}
+	if err := f.reload(); err != nil {
+		return nil, err
+	}
+	if interval > 0 {
+		go f.watch(interval)
+	}
+	return f, nil
+}
+
+func (f *FileResolver) Resolve(ctx context.Context, serviceName string, port uint) ([]Endpoint, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.registry.Resolve(ctx, serviceName, port)
+}
+
+// Close stops watching the registry file.
+func (f *FileResolver) Close() {
+	f.once.Do(func() {
+		close(f.stop)
+	})
+}
+
+func (f *FileResolver) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+			if err := f.reload(); err != nil {
+				DefaultLogger.Warn("failed to reload registry file", slog.String("path", f.path), slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// reload parses the registry file if it has been modified since last load, the current registry is kept if failed.
+func (f *FileResolver) reload() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	f.mu.RLock()
+	modified := !info.ModTime().Equal(f.modTime)
+	f.mu.RUnlock()
+	if !modified {
+		return nil
+	}
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	var raw map[string][]string
+	switch strings.ToLower(filepath.Ext(f.path)) {
+	case ".yaml", ".yml":
+		raw, err = parseYamlRegistry(data)
+	default:
+		err = json.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return fmt.Errorf("malformed registry file %s: %v", f.path, err)
+	}
+	registry := make(StaticResolver, len(raw))
+	for name, addresses := range raw {
+		for _, address := range addresses {
+			endpoint, err := ParseEndpoint(address)
+			if err != nil {
+				return fmt.Errorf("malformed registry file %s: %v", f.path, err)
+			}
+			registry[name] = append(registry[name], endpoint)
+		}
+	}
+	f.mu.Lock()
+	f.registry = registry
+	f.modTime = info.ModTime()
+	f.mu.Unlock()
+	return nil
+}
+
+func parseYamlRegistry(data []byte) (map[string][]string, error) {
+	registry := make(map[string][]string)
+	var current string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case trimmed == "":
+			continue
+		case strings.HasPrefix(trimmed, "-"):
+			if current == "" {
+				return nil, fmt.Errorf("line %d: sequence item without service name", line)
+			}
+			registry[current] = append(registry[current], unquoteYaml(strings.TrimSpace(trimmed[1:])))
+		case strings.HasSuffix(trimmed, ":") && text == strings.TrimLeft(text, " \t"):
+			current = unquoteYaml(strings.TrimSuffix(trimmed, ":"))
+			registry[current] = nil
+		default:
+			return nil, fmt.Errorf("line %d: unsupported syntax %q", line, trimmed)
+		}
+	}
+	return registry, scanner.Err()
+}
+
+func unquoteYaml(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,169 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    Endpoint
+		wantErr bool
+	}{
+		{name: "host", s: "billing", want: Endpoint{Host: "billing"}},
+		{name: "host and port", s: "10.0.0.1:8080", want: Endpoint{Host: "10.0.0.1", Port: 8080}},
+		{name: "ipv6", s: "[::1]:8080", want: Endpoint{Host: "::1", Port: 8080}},
+		{name: "invalid port", s: "billing:abc", wantErr: true},
+		{name: "empty", s: " ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseEndpoint(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseEndpoint() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseEndpoint() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetUrlWithResolver(t *testing.T) {
+	resolver := StaticResolver{
+		"billing": {{Host: "10.0.0.1"}},
+		"order":   {{Host: "10.0.0.2", Port: 9090}},
+	}
+	tests := []struct {
+		name    string
+		req     *Request
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "request port",
+			req:  Req().WithResolver(resolver).HostAndPort("billing", 8080).WithPath("invoices"),
+			want: "http://10.0.0.1:8080/invoices",
+		},
+		{
+			name: "resolver port",
+			req:  Req().WithResolver(resolver).HostAndPort("order", 8080).WithPath("orders"),
+			want: "http://10.0.0.2:9090/orders",
+		},
+		{
+			name: "secure default port",
+			req:  Req().WithResolver(resolver).Host("billing").WithSecure(true),
+			want: "https://10.0.0.1:443",
+		},
+		{
+			name: "host name takes precedence",
+			req:  Req().WithResolver(resolver).Host("billing").WithHostName("http://localhost"),
+			want: "http://localhost",
+		},
+		{
+			name:    "unknown service",
+			req:     Req().WithResolver(resolver).Host("unknown"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetUrl(tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetUrl() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetUrl() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileResolver(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		modified string
+		want     []Endpoint
+		wantErr  bool
+	}{
+		{
+			name:     "json",
+			file:     "registry.json",
+			content:  `{"billing": ["10.0.0.1:8080", "10.0.0.2"]}`,
+			modified: `{"billing": ["10.0.0.3:8080"]}`,
+			want:     []Endpoint{{Host: "10.0.0.3", Port: 8080}},
+		},
+		{
+			name: "yaml",
+			file: "registry.yaml",
+			content: `# services
+billing:
+  - 10.0.0.1:8080
+  - "10.0.0.2"
+`,
+			modified: `billing:
+  - 10.0.0.3:8080
+`,
+			want: []Endpoint{{Host: "10.0.0.3", Port: 8080}},
+		},
+		{
+			name:    "malformed",
+			file:    "registry.yaml",
+			content: `billing: [10.0.0.1]`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			resolver, err := NewFileResolver(path, 10*time.Millisecond)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewFileResolver() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			defer resolver.Close()
+
+			got, err := resolver.Resolve(context.Background(), "billing", 80)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []Endpoint{{Host: "10.0.0.1", Port: 8080}, {Host: "10.0.0.2", Port: 80}}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Resolve() got = %v, want %v", got, want)
+			}
+
+			if err = ioutil.WriteFile(path, []byte(tt.modified), 0644); err != nil {
+				t.Fatal(err)
+			}
+			later := time.Now().Add(time.Second)
+			if err = os.Chtimes(path, later, later); err != nil {
+				t.Fatal(err)
+			}
+			deadline := time.Now().Add(time.Second)
+			for time.Now().Before(deadline) {
+				if got, _ = resolver.Resolve(context.Background(), "billing", 80); reflect.DeepEqual(got, tt.want) {
+					return
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			t.Errorf("Resolve() after reload got = %v, want %v", got, tt.want)
+		})
+	}
+}
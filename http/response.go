@@ -3,6 +3,7 @@ package http
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 )
 
@@ -13,6 +14,13 @@ type Response interface {
 	HttpResponse() *http.Response
 }
 
+// BodyTarget could be implemented by Response to decode response body into the returned value rather than
+// the Response itself. Decoding is skipped if the returned value is nil.
+type BodyTarget interface {
+	Target() interface{}
+}
+
+// DefaultResponse decodes response body into Data, which should be a pointer. The body is discarded if Data is nil.
 type DefaultResponse struct {
 	err  error
 	Data interface{} `json:"data"`
@@ -35,6 +43,10 @@ func (d *DefaultResponse) HttpResponse() *http.Response {
 	return d.Raw
 }
 
+func (d *DefaultResponse) Target() interface{} {
+	return d.Data
+}
+
 func (d *DefaultResponse) String() string {
 	if d.err != nil {
 		return fmt.Sprintf("err: %v", d.err)
@@ -43,6 +55,52 @@ func (d *DefaultResponse) String() string {
 }
 
 func (d *DefaultResponse) UnmarshalJSON(b []byte) error {
+	if d.Data == nil {
+		return nil
+	}
 	err := json.Unmarshal(b, d.Data)
 	return err
 }
+
+// StreamReceiver could be implemented by Response to take over the live response body rather than letting it be read
+// into memory. The receiver owns the body and must close it. Bodies of error responses are never streamed,
+// they are read into StatusError instead.
+type StreamReceiver interface {
+	SetBody(body io.ReadCloser)
+}
+
+// StreamResponse hands the live response body to caller, who must close it after consuming.
+// If the Request has a timeout, the timeout keeps counting until Body is closed.
+type StreamResponse struct {
+	Body io.ReadCloser
+	err  error
+	Raw  *http.Response
+}
+
+func (s *StreamResponse) Error() error {
+	return s.err
+}
+
+func (s *StreamResponse) ErrorSave(e error) {
+	s.err = e
+}
+
+func (s *StreamResponse) SetRaw(raw *http.Response) {
+	s.Raw = raw
+}
+
+func (s *StreamResponse) HttpResponse() *http.Response {
+	return s.Raw
+}
+
+func (s *StreamResponse) SetBody(body io.ReadCloser) {
+	s.Body = body
+}
+
+// Close closes the response body if there is one.
+func (s *StreamResponse) Close() error {
+	if s.Body == nil {
+		return nil
+	}
+	return s.Body.Close()
+}
@@ -0,0 +1,92 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamResponse(t *testing.T) {
+	next := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("not found"))
+			return
+		}
+		for i := 0; i < 3; i++ {
+			_, _ = w.Write([]byte("line\n"))
+			w.(http.Flusher).Flush()
+			select {
+			case <-next:
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	t.Run("incremental", func(t *testing.T) {
+		var logged bool
+		rsp := &StreamResponse{}
+		Req().WithHostName(server.URL).WithTimeout(5 * time.Second).Use(func(ctx *Context) {
+			ctx.Next()
+			logged = true
+		}).Get(rsp)
+		if rsp.Error() != nil {
+			t.Fatal(rsp.Error())
+		}
+		defer rsp.Close()
+		if !logged || rsp.HttpResponse().StatusCode != http.StatusOK {
+			t.Fatal("StreamResponse should be returned once headers arrive")
+		}
+		reader := bufio.NewReader(rsp.Body)
+		for i := 0; i < 3; i++ {
+			line, err := reader.ReadString('\n')
+			if err != nil || line != "line\n" {
+				t.Fatalf("StreamResponse read line %d = %q, %v", i, line, err)
+			}
+			next <- struct{}{}
+		}
+		if rest, err := ioutil.ReadAll(reader); err != nil || len(rest) != 0 {
+			t.Errorf("StreamResponse rest = %q, %v", rest, err)
+		}
+	})
+
+	t.Run("close cancels timeout context", func(t *testing.T) {
+		var reqCtx context.Context
+		rsp := &StreamResponse{}
+		Req().WithHostName(server.URL).WithTimeout(5 * time.Second).Use(func(ctx *Context) {
+			reqCtx = ctx.Context
+			ctx.Next()
+		}).Get(rsp)
+		if rsp.Error() != nil {
+			t.Fatal(rsp.Error())
+		}
+		if reqCtx.Err() != nil {
+			t.Fatal("context canceled before the body is closed")
+		}
+		_ = rsp.Close()
+		if !errors.Is(reqCtx.Err(), context.Canceled) {
+			t.Errorf("context error after close = %v", reqCtx.Err())
+		}
+	})
+
+	t.Run("error response is read", func(t *testing.T) {
+		rsp := &StreamResponse{}
+		Req().WithHostName(server.URL).WithPath("error").Get(rsp)
+		var statusErr *StatusError
+		if !errors.As(rsp.Error(), &statusErr) || !strings.Contains(string(statusErr.Body), "not found") {
+			t.Errorf("StreamResponse error = %v", rsp.Error())
+		}
+		if rsp.Body != nil {
+			t.Error("StreamResponse body of error response should not be streamed")
+		}
+	})
+}
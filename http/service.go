@@ -1,24 +1,52 @@
 package http
 
 import (
+	"context"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
 // Service represents a remote service
+//
+// The remote service could be served by multiple endpoints, either listed in Hosts or resolved from Name and Port
+// by Resolver. Every attempt of the Request created by Serve picks one endpoint according to LoadBalance.
 type Service struct {
+	// Parent is the Client which Requests are created from, DefaultClient is used if it is nil.
+	Parent      *Client
 	Client      *http.Client
 	Host        string
-	Middlewares []HandlerFunc
-	Headers     map[string]string
-	Timeout     string
-	Secure      bool
+	Hosts       []string
+	Name        string
+	Port        uint
+	Resolver    Resolver
+	LoadBalance LoadBalanceConfig
+	// StatusPolicy decides which responses are errors, DefaultStatusPolicy is used if it is nil.
+	StatusPolicy *StatusPolicy
+	Middlewares  []HandlerFunc
+	Headers      map[string]string
+	Timeout      string
+	Secure       bool
+	// balancer is the *serviceBalancer built by Serve, it is shared by the copies of Service made after it.
+	balancer atomic.Value
 }
 
 // Serve create Request from Service
 func (s *Service) Serve() *Request {
-	request := Req().WithHostName(s.Host).Use(s.Middlewares...).WithHeaders(s.Headers).WithSecure(s.Secure)
-	request.Client = s.Client
+	parent := s.Parent
+	if parent == nil {
+		parent = DefaultClient
+	}
+	request := parent.Req().WithHostName(s.Host).Use(s.Middlewares...).WithHeaders(s.Headers).WithSecure(s.Secure)
+	if s.Client != nil {
+		request.Client = s.Client
+	}
+	request.Resolver = s.Resolver
+	request.StatusPolicy = s.StatusPolicy
+	if len(s.Hosts) > 0 || s.Name != "" {
+		request.balancer = s.loadBalancer()
+	}
 	if s.Timeout != "" {
 		duration, err := time.ParseDuration(s.Timeout)
 		request.err = err
@@ -26,3 +54,54 @@ func (s *Service) Serve() *Request {
 	}
 	return request
 }
+
+// serviceBalancer is the balancer built from the endpoints of Service at that moment.
+type serviceBalancer struct {
+	hosts  []string
+	name   string
+	port   uint
+	secure bool
+	lb     *loadBalancer
+}
+
+func (b *serviceBalancer) builtFrom(s *Service) bool {
+	if b.name != s.Name || b.port != s.Port || b.secure != s.Secure || len(b.hosts) != len(s.Hosts) {
+		return false
+	}
+	for i, host := range b.hosts {
+		if host != s.Hosts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// loadBalancer returns the balancer of Service, which is built again once the endpoints of Service are changed,
+// ie: by a copy of Service.
+func (s *Service) loadBalancer() *loadBalancer {
+	for {
+		old := s.balancer.Load()
+		if current, ok := old.(*serviceBalancer); ok && current.builtFrom(s) {
+			return current.lb
+		}
+		b := &serviceBalancer{hosts: append([]string(nil), s.Hosts...), name: s.Name, port: s.Port, secure: s.Secure}
+		resolver := s.Resolver
+		if resolver == nil {
+			resolver = passthroughResolver{}
+		}
+		b.lb = newLoadBalancer(s.LoadBalance, func(ctx context.Context) ([]string, error) {
+			if len(b.hosts) > 0 {
+				return b.hosts, nil
+			}
+			return resolvedHosts(ctx, resolver, b.name, b.port, b.secure)
+		})
+		b.lb.name = b.name
+		if b.lb.name == "" {
+			b.lb.name = strings.Join(b.hosts, ",")
+		}
+		// the balancer built by a concurrent call wins
+		if s.balancer.CompareAndSwap(old, b) {
+			return b.lb
+		}
+	}
+}
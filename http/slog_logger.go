@@ -0,0 +1,118 @@
+package http
+
+import (
+	"log/slog"
+	"time"
+)
+
+// DefaultLogger is the logger of Recovery, SlogLogger without logger and the warnings of this package, ie: overriding
+// ContentTypeResolver. By default it writes text records into DefaultWriter, it could be replaced by a JSON logger.
+var DefaultLogger = slog.New(slog.NewTextHandler(defaultWriter{}, nil))
+
+// defaultWriter writes into DefaultWriter, so that replacing DefaultWriter takes effect in DefaultLogger.
+type defaultWriter struct{}
+
+func (defaultWriter) Write(p []byte) (int, error) {
+	return DefaultWriter.Write(p)
+}
+
+type SlogOptions struct {
+	// Message is the message of records, default is "http request".
+	Message string
+	// Level decides the level of record by the outcome of Request, default is DefaultSlogLevel.
+	Level func(param LogFormatterParams) slog.Level
+	// LogQuery, LogHeaders, LogBody, MaxBodySize and Redaction are the same as the ones of LoggerConfig.
+	LogQuery    bool
+	LogHeaders  bool
+	LogBody     bool
+	MaxBodySize int
+	Redaction   Redaction
+}
+
+// DefaultSlogLevel logs failed Requests at error level, except 4xx responses which are at warn level, and others
+// at info level.
+func DefaultSlogLevel(param LogFormatterParams) slog.Level {
+	switch {
+	case param.StatusCode >= 500:
+		return slog.LevelError
+	case param.StatusCode >= 400:
+		return slog.LevelWarn
+	case param.ErrMessage != "":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// SlogLogger returns a middleware that logs every Request as a structured record into logger, DefaultLogger is used
+// if logger is nil. The attributes are method, host, path_template, path, status, latency, bytes, attempt, error
+// and error_kind if the Request fails, and the group timings if the Request is sent. The redacted query,
+// request_headers, request_body, response_headers and response_body are added if they are enabled by opts.
+func SlogLogger(logger *slog.Logger, opts SlogOptions) HandlerFunc {
+	message := opts.Message
+	if message == "" {
+		message = "http request"
+	}
+	level := opts.Level
+	if level == nil {
+		level = DefaultSlogLevel
+	}
+	details := newLogDetails(opts.LogQuery, opts.LogHeaders, opts.LogBody, opts.MaxBodySize, opts.Redaction)
+	return func(ctx *Context) {
+		l := logger
+		if l == nil {
+			l = DefaultLogger
+		}
+		start := time.Now()
+		details.prepare(ctx)
+
+		ctx.Next()
+
+		param := newLogFormatterParams(ctx, start)
+		lvl := level(param)
+		if !l.Enabled(ctx.Context, lvl) {
+			return
+		}
+		details.fill(ctx, &param)
+		attrs := []slog.Attr{
+			slog.String("method", param.Method),
+			slog.String("host", param.Host),
+			slog.String("path_template", "/"+param.PathTemplate),
+			slog.String("path", "/"+param.Path),
+			slog.Int("status", param.StatusCode),
+			slog.Duration("latency", param.Latency),
+			slog.Int64("bytes", param.ResponseSize),
+			slog.Int("attempt", ctx.Attempt()),
+		}
+		if param.ErrMessage != "" {
+			attrs = append(attrs, slog.String("error", param.ErrMessage), slog.String("error_kind", param.ErrKind))
+		}
+		if timings := param.Timings; timings != (Timings{}) {
+			attrs = append(attrs, slog.Group("timings",
+				slog.Duration("conn_wait", timings.ConnWait),
+				slog.Duration("dns", timings.DNS),
+				slog.Duration("connect", timings.Connect),
+				slog.Duration("tls", timings.TLSHandshake),
+				slog.Duration("ttfb", timings.TimeToFirstByte),
+				slog.Duration("body_read", timings.BodyRead),
+				slog.Bool("conn_reused", timings.ConnReused),
+			))
+		}
+		if param.Query != "" {
+			attrs = append(attrs, slog.String("query", param.Query))
+		}
+		if param.RequestHeader != nil {
+			attrs = append(attrs, slog.Any("request_headers", param.RequestHeader))
+		}
+		if param.RequestBody != "" {
+			attrs = append(attrs, slog.String("request_body", param.RequestBody))
+		}
+		if param.ResponseHeader != nil {
+			attrs = append(attrs, slog.Any("response_headers", param.ResponseHeader))
+		}
+		if param.ResponseBody != "" {
+			attrs = append(attrs, slog.String("response_body", param.ResponseBody))
+		}
+		l.LogAttrs(ctx.Context, lvl, message, attrs...)
+	}
+}
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func decodeRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		record := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("record %s is not JSON: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestSlogLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/404":
+			w.WriteHeader(http.StatusNotFound)
+		case "/users/500":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			_, _ = w.Write([]byte(`{"id":1}`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		hostName  string
+		id        int
+		wantLevel string
+		want      map[string]interface{}
+	}{
+		{
+			name:      "success",
+			hostName:  server.URL,
+			id:        1,
+			wantLevel: "INFO",
+			want:      map[string]interface{}{"status": 200.0, "bytes": 8.0, "attempt": 1.0, "path": "/users/1"},
+		},
+		{
+			name:      "client error",
+			hostName:  server.URL,
+			id:        404,
+			wantLevel: "WARN",
+			want:      map[string]interface{}{"status": 404.0, "error_kind": "status"},
+		},
+		{
+			name:      "server error",
+			hostName:  server.URL,
+			id:        500,
+			wantLevel: "ERROR",
+			want:      map[string]interface{}{"status": 500.0, "error_kind": "status"},
+		},
+		{
+			name:      "transport error",
+			hostName:  "http://127.0.0.1:1",
+			id:        1,
+			wantLevel: "ERROR",
+			want:      map[string]interface{}{"status": 0.0, "error_kind": "transport"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, nil))
+			Req().WithHostName(tt.hostName).WithPath("/users/{id}").PathParam("id", tt.id).
+				Use(LoggerWithConfig(LoggerConfig{Logger: logger})).Get(&DefaultResponse{})
+
+			records := decodeRecords(t, &buf)
+			if len(records) != 1 {
+				t.Fatalf("SlogLogger() logged %d records, want 1", len(records))
+			}
+			record := records[0]
+			if record["level"] != tt.wantLevel || record["msg"] != "http request" || record["method"] != "GET" ||
+				record["path_template"] != "/users/{id}" {
+				t.Errorf("SlogLogger() record = %v", record)
+			}
+			for key, want := range tt.want {
+				if record[key] != want {
+					t.Errorf("SlogLogger() %s = %v, want %v", key, record[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultLogger(t *testing.T) {
+	var buf bytes.Buffer
+	origin := DefaultLogger
+	DefaultLogger = slog.New(slog.NewJSONHandler(&buf, nil))
+	defer func() {
+		DefaultLogger = origin
+	}()
+
+	rsp := &DefaultResponse{}
+	Req().WithPath("/orders").Use(Recovery(), func(ctx *Context) {
+		panic("boom")
+	}).Get(rsp)
+	if rsp.Error() == nil || rsp.Error().Error() != "boom" {
+		t.Errorf("Recovery() error = %v, want boom", rsp.Error())
+	}
+	RegisterContentResolver(ContentTypeJson, &contentTypeJson{})
+
+	records := decodeRecords(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("DefaultLogger logged %d records, want 2", len(records))
+	}
+	if panicked := records[0]; panicked["level"] != "ERROR" || panicked["panic"] != "boom" ||
+		panicked["path_template"] != "/orders" || !strings.Contains(panicked["stacktrace"].(string), "goroutine") {
+		t.Errorf("Recovery() record = %v", panicked)
+	}
+	if override := records[1]; override["level"] != "WARN" || override["content_type"] != ContentTypeJson {
+		t.Errorf("RegisterContentResolver() record = %v", override)
+	}
+}
@@ -0,0 +1,189 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ContentTypeEventStream = "text/event-stream"
+	lastEventIDHeader      = "Last-Event-ID"
+	defaultSSERetry        = 3 * time.Second
+)
+
+// Event is a message received from Server-Sent Events stream.
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+	// Retry is the reconnection time sent by server, zero if absent.
+	Retry time.Duration
+}
+
+// SSE connects to a text/event-stream through the handler chain of current Request, and calls handler for every
+// received event. The connection is re-established with Last-Event-ID whenever it is lost, after the delay sent by
+// server in `retry` field, which is 3 seconds by default.
+//
+// SSE returns when handler returns an error, the context of Request is done, the server responds 204 No Content
+// or an error status, the response is not an event stream, or the Request fails with an error other than a lost
+// connection, ie: ValidationErrors or errors of middlewares. Note that Timeout applies to every connection.
+func (r *Request) SSE(handler func(Event) error) error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.ctx.Context == nil {
+		r.ctx.Context = context.Background()
+	}
+	r.WithHeaders(map[string]string{
+		AcceptTypeHeader: ContentTypeEventStream,
+		"Cache-Control":  "no-cache",
+	})
+	parser := &eventParser{retry: defaultSSERetry}
+	for {
+		if parser.lastID != "" {
+			r.Headers[lastEventIDHeader] = parser.lastID
+		}
+		rsp := &StreamResponse{}
+		r.ctx.Method = http.MethodGet
+		r.do(rsp)
+
+		if err := rsp.Error(); err != nil {
+			if !r.reconnectable(err) {
+				return err
+			}
+		} else {
+			done, err := r.consumeEvents(rsp, parser, handler)
+			if done {
+				return err
+			}
+		}
+
+		timer := time.NewTimer(parser.retry)
+		select {
+		case <-r.ctx.Context.Done():
+			timer.Stop()
+			return r.ctx.Context.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reconnectable reports whether err is a lost connection, which is the only kind of error SSE reconnects on, since
+// the others fail the same way after reconnecting.
+func (r *Request) reconnectable(err error) bool {
+	if r.ctx.Context.Err() != nil {
+		return false
+	}
+	var urlErr *url.Error
+	var netErr net.Error
+	return errors.As(err, &urlErr) || errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// consumeEvents reads events until the stream ends, it reports whether SSE should stop rather than reconnecting.
+func (r *Request) consumeEvents(rsp *StreamResponse, parser *eventParser, handler func(Event) error) (bool, error) {
+	defer func() {
+		_ = rsp.Close()
+	}()
+	if rsp.HttpResponse().StatusCode == http.StatusNoContent {
+		return true, nil
+	}
+	if contentType := rsp.HttpResponse().Header.Get(ContentTypeHeader); mediaType(contentType) != ContentTypeEventStream {
+		return true, fmt.Errorf("unexpected content type %s of event stream", contentType)
+	}
+	if err := parser.parse(rsp.Body, handler); err != nil {
+		return true, err
+	}
+	if ctxErr := r.ctx.Context.Err(); ctxErr != nil {
+		return true, ctxErr
+	}
+	return false, nil
+}
+
+type eventParser struct {
+	lastID string
+	retry  time.Duration
+}
+
+// parse reads event stream until it ends, only the error returned by handler is returned.
+func (p *eventParser) parse(body io.Reader, handler func(Event) error) error {
+	reader := bufio.NewReader(body)
+	var data bytes.Buffer
+	var event Event
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			// the stream is lost or ended, incomplete event is discarded
+			return nil
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			// blank line dispatches the event
+			if data.Len() > 0 {
+				event.ID = p.lastID
+				event.Data = strings.TrimSuffix(data.String(), "\n")
+				if event.Event == "" {
+					event.Event = "message"
+				}
+				if err = handler(event); err != nil {
+					return err
+				}
+			}
+			data.Reset()
+			event = Event{}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				p.lastID = value
+			}
+		case "retry":
+			if ms, convErr := strconv.ParseUint(value, 10, 63); convErr == nil {
+				p.retry = time.Duration(ms) * time.Millisecond
+				event.Retry = p.retry
+			}
+		}
+	}
+}
+
+type contentTypeEventStream struct{}
+
+func (c *contentTypeEventStream) Marshal(v interface{}) ([]byte, error) {
+	return nil, errors.New("marshal text/event-stream is not supported")
+}
+
+// Unmarshal parses a complete event stream into *[]Event.
+func (c *contentTypeEventStream) Unmarshal(data []byte, v interface{}) error {
+	events, ok := v.(*[]Event)
+	if !ok {
+		return fmt.Errorf("unmarshal text/event-stream into %T, *[]Event required", v)
+	}
+	parser := &eventParser{}
+	return parser.parse(bytes.NewReader(data), func(event Event) error {
+		*events = append(*events, event)
+		return nil
+	})
+}
@@ -0,0 +1,160 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+func TestRequestSSE(t *testing.T) {
+	stop := errors.New("stop")
+	connections := atomic.Int32{}
+	var lastEventIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/no-content":
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		case "/json":
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		lastEventIDs = append(lastEventIDs, r.Header.Get(lastEventIDHeader))
+		w.Header().Set(ContentTypeHeader, ContentTypeEventStream)
+		if connections.Inc() == 1 {
+			_, _ = w.Write([]byte("retry: 10\n\nid: 1\ndata: a\n\n: comment\nevent: update\nid: 2\ndata: b\r\ndata: c\n\ndata: incomplete"))
+			return
+		}
+		_, _ = w.Write([]byte("id: 3\ndata:d\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	t.Run("reconnect", func(t *testing.T) {
+		var events []Event
+		chained := atomic.Int32{}
+		err := Req().WithHostName(server.URL).WithPath("events").Use(func(ctx *Context) {
+			chained.Inc()
+			ctx.Next()
+		}).SSE(func(event Event) error {
+			events = append(events, event)
+			if event.ID == "3" {
+				return stop
+			}
+			return nil
+		})
+		if !errors.Is(err, stop) {
+			t.Errorf("Request.SSE() error = %v, want %v", err, stop)
+		}
+		want := []Event{
+			{ID: "1", Event: "message", Data: "a"},
+			{ID: "2", Event: "update", Data: "b\nc"},
+			{ID: "3", Event: "message", Data: "d"},
+		}
+		if !reflect.DeepEqual(events, want) {
+			t.Errorf("Request.SSE() events = %+v, want %+v", events, want)
+		}
+		if !reflect.DeepEqual(lastEventIDs, []string{"", "2"}) {
+			t.Errorf("Request.SSE() Last-Event-ID = %v", lastEventIDs)
+		}
+		if chained.Load() != 2 {
+			t.Errorf("Request.SSE() chain executed %d times, want 2", chained.Load())
+		}
+	})
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "no content", path: "no-content"},
+		{name: "error status", path: "error", wantErr: true},
+		{name: "not event stream", path: "json", wantErr: true},
+	}
+	t.Run("not reconnect on malformed request", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		chained := atomic.Int32{}
+		query := struct {
+			Name string `query:"name,required"`
+		}{}
+		err := Req().WithContext(ctx).WithHostName(server.URL).WithPath("events").WithQueries(query).Use(func(ctx *Context) {
+			chained.Inc()
+			ctx.Next()
+		}).SSE(func(event Event) error {
+			return nil
+		})
+		var validationErrs ValidationErrors
+		if !errors.As(err, &validationErrs) || ctx.Err() != nil || chained.Load() != 0 {
+			t.Errorf("Request.SSE() error = %v, chain executed %d times, want validation error at once", err, chained.Load())
+		}
+
+		err = Req().WithContext(ctx).WithHostName(server.URL).WithPath("events/{id}").Use(func(ctx *Context) {
+			chained.Inc()
+			ctx.Next()
+		}).SSE(func(event Event) error {
+			return nil
+		})
+		if err == nil || ctx.Err() != nil || chained.Load() != 1 {
+			t.Errorf("Request.SSE() error = %v, chain executed %d times, want missing path parameter at once", err, chained.Load())
+		}
+	})
+
+	t.Run("not reconnect on middleware error", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		err := Req().WithContext(ctx).WithHostName(server.URL).WithPath("events").Use(func(ctx *Context) {
+			ctx.Response.ErrorSave(RateLimitExceedError)
+			ctx.Abort()
+		}).SSE(func(event Event) error {
+			return nil
+		})
+		if !errors.Is(err, RateLimitExceedError) || ctx.Err() != nil {
+			t.Errorf("Request.SSE() error = %v, want %v at once", err, RateLimitExceedError)
+		}
+	})
+
+	t.Run("reconnect on lost connection", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		err := Req().WithContext(ctx).WithHostName("http://127.0.0.1:1").SSE(func(event Event) error {
+			return nil
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Request.SSE() error = %v, want reconnecting until %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Req().WithHostName(server.URL).WithPath(tt.path).WithTimeout(time.Second).SSE(func(event Event) error {
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Request.SSE() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_contentTypeEventStream_Unmarshal(t *testing.T) {
+	var events []Event
+	err := (&contentTypeEventStream{}).Unmarshal([]byte("event: a\ndata: 1\nretry: 5000\n\ndata: 2\n\n"), &events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Event{{Event: "a", Data: "1", Retry: 5 * time.Second}, {Event: "message", Data: "2"}}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("Unmarshal() got = %+v, want %+v", events, want)
+	}
+}
@@ -0,0 +1,129 @@
+package http
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const ContentTypeProblemJson = "application/problem+json"
+
+// DefaultStatusPolicy treats every non-2xx response as error.
+var DefaultStatusPolicy = &StatusPolicy{}
+
+// AcceptAllStatusPolicy never treats response as error by its status code, response body is always decoded into
+// Response.
+var AcceptAllStatusPolicy = &StatusPolicy{
+	IsError: func(statusCode int) bool {
+		return false
+	},
+}
+
+// StatusPolicy decides which HTTP responses are errors, and how their bodies are decoded.
+type StatusPolicy struct {
+	// IsError reports whether the status code should be treated as error. By default, non-2xx status codes are errors.
+	IsError func(statusCode int) bool
+	// ErrorBody returns a new pointer that the error body will be decoded into, using the ContentTypeResolver of
+	// response Content-Type. If it is nil or returns nil, application/problem+json bodies are decoded into Problem,
+	// and others are kept raw in StatusError.
+	ErrorBody func(statusCode int) interface{}
+}
+
+func (p *StatusPolicy) isError(statusCode int) bool {
+	if p.IsError != nil {
+		return p.IsError(statusCode)
+	}
+	return statusCode < http.StatusOK || statusCode >= http.StatusMultipleChoices
+}
+
+// newError builds StatusError from the response, and decodes the body if its content type could be resolved.
+func (p *StatusPolicy) newError(client *Client, rsp *http.Response, body []byte) *StatusError {
+	statusErr := &StatusError{
+		StatusCode: rsp.StatusCode,
+		Header:     rsp.Header,
+		Body:       body,
+	}
+	if len(body) == 0 {
+		return statusErr
+	}
+	mediaType, _, _ := mime.ParseMediaType(rsp.Header.Get(ContentTypeHeader))
+	var detail interface{}
+	if p.ErrorBody != nil {
+		detail = p.ErrorBody(rsp.StatusCode)
+	}
+	if detail == nil && mediaType == ContentTypeProblemJson {
+		detail = &Problem{}
+	}
+	if detail == nil {
+		return statusErr
+	}
+	resolver, ok := client.contentResolver(mediaType)
+	if !ok {
+		return statusErr
+	}
+	if err := resolver.Unmarshal(body, detail); err == nil {
+		statusErr.Detail = detail
+	}
+	return statusErr
+}
+
+// StatusError is saved into Response when the status code is treated as error by StatusPolicy.
+// If the decoded Detail is an error, it could be obtained by errors.As.
+type StatusError struct {
+	StatusCode int
+	Header     http.Header
+	// Body is the raw response body.
+	Body []byte
+	// Detail is the decoded response body, it is nil if the body could not be decoded.
+	Detail interface{}
+}
+
+func (e *StatusError) Error() string {
+	var detail string
+	switch d := e.Detail.(type) {
+	case error:
+		detail = d.Error()
+	case nil:
+		detail = strings.TrimSpace(string(e.Body))
+		if len(detail) > 256 {
+			detail = detail[:256] + "..."
+		}
+	default:
+		detail = fmt.Sprintf("%v", d)
+	}
+	if detail == "" {
+		return fmt.Sprintf("unexpected status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("unexpected status %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), detail)
+}
+
+func (e *StatusError) Unwrap() error {
+	if err, ok := e.Detail.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// Problem is the problem details for HTTP APIs defined by RFC 7807.
+type Problem struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+func (p *Problem) Error() string {
+	msg := p.Title
+	if msg == "" {
+		msg = p.Type
+	}
+	if p.Detail != "" {
+		if msg != "" {
+			msg += ": "
+		}
+		msg += p.Detail
+	}
+	return msg
+}
@@ -0,0 +1,140 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type apiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+func TestStatusPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/problem":
+			w.Header().Set(ContentTypeHeader, ContentTypeProblemJson)
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"type": "about:blank", "title": "Not Found", "status": 404, "detail": "user 1 not found"}`))
+		case "/api":
+			w.Header().Set(ContentTypeHeader, ContentTypeJson+"; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"code": 1001, "message": "database unavailable"}`))
+		case "/text":
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(`upstream down`))
+		default:
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"code": 0}`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		path       string
+		policy     *StatusPolicy
+		wantStatus int
+		wantDetail interface{}
+		wantErr    bool
+	}{
+		{
+			name: "2xx",
+			path: "ok",
+		},
+		{
+			name:       "problem json",
+			path:       "problem",
+			wantStatus: http.StatusNotFound,
+			wantDetail: &Problem{Type: "about:blank", Title: "Not Found", Status: 404, Detail: "user 1 not found"},
+			wantErr:    true,
+		},
+		{
+			name: "registered error body",
+			path: "api",
+			policy: &StatusPolicy{ErrorBody: func(statusCode int) interface{} {
+				return &apiError{}
+			}},
+			wantStatus: http.StatusInternalServerError,
+			wantDetail: &apiError{Code: 1001, Message: "database unavailable"},
+			wantErr:    true,
+		},
+		{
+			name:       "raw body",
+			path:       "text",
+			wantStatus: http.StatusBadGateway,
+			wantErr:    true,
+		},
+		{
+			name:    "accept all",
+			path:    "api",
+			policy:  AcceptAllStatusPolicy,
+			wantErr: false,
+		},
+		{
+			name: "custom status codes",
+			path: "ok",
+			policy: &StatusPolicy{IsError: func(statusCode int) bool {
+				return statusCode != http.StatusOK
+			}},
+			wantStatus: http.StatusAccepted,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]interface{}{}
+			rsp := &DefaultResponse{Data: &data}
+			Req().WithHostName(server.URL).WithPath(tt.path).WithStatusPolicy(tt.policy).Get(rsp)
+			if (rsp.Error() != nil) != tt.wantErr {
+				t.Errorf("Request.Get() error = %v, wantErr %v", rsp.Error(), tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				if len(data) == 0 {
+					t.Error("Request.Get() body not decoded")
+				}
+				return
+			}
+			var statusErr *StatusError
+			if !errors.As(rsp.Error(), &statusErr) {
+				t.Fatalf("Request.Get() error = %v, want StatusError", rsp.Error())
+			}
+			if statusErr.StatusCode != tt.wantStatus || len(statusErr.Body) == 0 {
+				t.Errorf("StatusError = %v, want status %d", statusErr, tt.wantStatus)
+			}
+			if !reflect.DeepEqual(statusErr.Detail, tt.wantDetail) {
+				t.Errorf("StatusError.Detail = %v, want %v", statusErr.Detail, tt.wantDetail)
+			}
+			if len(data) != 0 {
+				t.Errorf("Request.Get() error body decoded into Response: %v", data)
+			}
+		})
+	}
+}
+
+func TestStatusErrorAs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ContentTypeHeader, ContentTypeProblemJson)
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"title": "Conflict", "detail": "version mismatch"}`))
+	}))
+	defer server.Close()
+
+	_, _, err := GetJSON[map[string]interface{}](Req().WithHostName(server.URL))
+	var problem *Problem
+	if !errors.As(err, &problem) {
+		t.Fatalf("GetJSON() error = %v, want Problem", err)
+	}
+	if problem.Error() != "Conflict: version mismatch" {
+		t.Errorf("Problem.Error() = %s", problem.Error())
+	}
+}
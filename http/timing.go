@@ -0,0 +1,132 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings breaks down the latency of the last attempt sent by doHttpReq, the phases which do not happen are zero,
+// ie: DNS, Connect and TLSHandshake of reused connections.
+type Timings struct {
+	// ConnWait is the time waiting for a connection from the pool, which includes DNS, Connect and TLSHandshake if
+	// a new connection is dialed.
+	ConnWait     time.Duration
+	DNS          time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+	// TimeToFirstByte is the time from the request being written to the first byte of response, which is mostly
+	// spent by the upstream.
+	TimeToFirstByte time.Duration
+	// BodyRead is the time from the response headers to the end of body, it is zero if the body is not finished
+	// before the handler chain returns, ie: streamed bodies.
+	BodyRead time.Duration
+	// ConnReused reports whether the connection had been used by previous requests.
+	ConnReused bool
+}
+
+// clientTrace collects Timings by httptrace, the hooks may be called from the dialing goroutines of transport.
+type clientTrace struct {
+	mu              sync.Mutex
+	timings         Timings
+	getConn         time.Time
+	dnsStart        time.Time
+	connectStart    time.Time
+	tlsStart        time.Time
+	wroteRequest    time.Time
+	headersReceived time.Time
+	bodyDone        bool
+}
+
+func newClientTrace(ctx context.Context) (*clientTrace, context.Context) {
+	c := &clientTrace{}
+	trace := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			c.mark(&c.getConn)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			c.mu.Lock()
+			c.timings.ConnWait = time.Since(c.getConn)
+			c.timings.ConnReused = info.Reused
+			c.mu.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			c.mark(&c.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			c.since(&c.dnsStart, &c.timings.DNS)
+		},
+		ConnectStart: func(string, string) {
+			c.mu.Lock()
+			// parallel dials of dual stack share the same start
+			if c.connectStart.IsZero() {
+				c.connectStart = time.Now()
+			}
+			c.mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				c.since(&c.connectStart, &c.timings.Connect)
+			}
+		},
+		TLSHandshakeStart: func() {
+			c.mark(&c.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			c.since(&c.tlsStart, &c.timings.TLSHandshake)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			c.mark(&c.wroteRequest)
+		},
+		GotFirstResponseByte: func() {
+			c.mu.Lock()
+			if !c.wroteRequest.IsZero() {
+				c.timings.TimeToFirstByte = time.Since(c.wroteRequest)
+			}
+			c.mu.Unlock()
+		},
+	}
+	return c, httptrace.WithClientTrace(ctx, trace)
+}
+
+func (c *clientTrace) mark(t *time.Time) {
+	c.mu.Lock()
+	*t = time.Now()
+	c.mu.Unlock()
+}
+
+func (c *clientTrace) since(start *time.Time, d *time.Duration) {
+	c.mu.Lock()
+	if !start.IsZero() {
+		*d = time.Since(*start)
+	}
+	c.mu.Unlock()
+}
+
+// gotHeaders marks the time the response headers are returned by transport.
+func (c *clientTrace) gotHeaders() {
+	if c != nil {
+		c.mark(&c.headersReceived)
+	}
+}
+
+// finishBody records BodyRead once the body is read to the end or closed.
+func (c *clientTrace) finishBody() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.bodyDone && !c.headersReceived.IsZero() {
+		c.bodyDone = true
+		c.timings.BodyRead = time.Since(c.headersReceived)
+	}
+}
+
+func (c *clientTrace) snapshot() Timings {
+	if c == nil {
+		return Timings{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.timings
+}
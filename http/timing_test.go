@@ -0,0 +1,79 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimings(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.Header().Set(ContentTypeHeader, ContentTypeJson)
+		_, _ = w.Write([]byte(`{"id":`))
+		w.(http.Flusher).Flush()
+		time.Sleep(delay)
+		_, _ = w.Write([]byte(`1}`))
+	}))
+	defer server.Close()
+
+	var got []Timings
+	record := func(ctx *Context) {
+		ctx.Next()
+		got = append(got, newLogFormatterParams(ctx, time.Now()).Timings)
+	}
+	httpClient := server.Client()
+	for i := 0; i < 2; i++ {
+		req := Req().WithHostName(server.URL).WithPath("/users/1").Use(record)
+		req.Client = httpClient
+		rsp := &DefaultResponse{}
+		req.Get(rsp)
+		if rsp.Error() != nil {
+			t.Fatalf("Get() error = %v", rsp.Error())
+		}
+	}
+
+	tests := []struct {
+		name    string
+		timings Timings
+		reused  bool
+	}{
+		{name: "new connection", timings: got[0], reused: false},
+		{name: "reused connection", timings: got[1], reused: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timings := tt.timings
+			if timings.ConnReused != tt.reused {
+				t.Errorf("ConnReused = %v, want %v", timings.ConnReused, tt.reused)
+			}
+			if dialed := timings.Connect > 0 && timings.TLSHandshake > 0; dialed == tt.reused {
+				t.Errorf("Connect = %v, TLSHandshake = %v, want dialed %v", timings.Connect, timings.TLSHandshake, !tt.reused)
+			}
+			if timings.ConnWait < timings.Connect+timings.TLSHandshake {
+				t.Errorf("ConnWait = %v, want at least Connect + TLSHandshake", timings.ConnWait)
+			}
+			if timings.TimeToFirstByte < delay {
+				t.Errorf("TimeToFirstByte = %v, want at least %v", timings.TimeToFirstByte, delay)
+			}
+			if timings.BodyRead < delay {
+				t.Errorf("BodyRead = %v, want at least %v", timings.BodyRead, delay)
+			}
+		})
+	}
+}
+
+func TestTimingsNotSent(t *testing.T) {
+	var timings Timings
+	Req().WithHostName("http://127.0.0.1:1").Use(func(ctx *Context) {
+		ctx.Next()
+		timings = newLogFormatterParams(ctx, time.Now()).Timings
+	}, func(ctx *Context) {
+		ctx.Abort()
+	}).Get(&DefaultResponse{})
+	if timings != (Timings{}) {
+		t.Errorf("Timings = %+v, want zero", timings)
+	}
+}
@@ -0,0 +1,332 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+
+	traceFlagSampled byte = 0x01
+)
+
+// DefaultSpanExporter exports the spans of Tracing(), spans are only propagated but not exported if it is nil.
+var DefaultSpanExporter SpanExporter
+
+// SpanContext identifies a span in a trace, it is propagated by W3C Trace Context headers.
+type SpanContext struct {
+	TraceID    [16]byte
+	SpanID     [8]byte
+	Flags      byte
+	TraceState string
+}
+
+// IsValid reports whether both TraceID and SpanID are not zero.
+func (s SpanContext) IsValid() bool {
+	return s.TraceID != [16]byte{} && s.SpanID != [8]byte{}
+}
+
+// TraceParent returns the value of traceparent header.
+func (s SpanContext) TraceParent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", hex.EncodeToString(s.TraceID[:]), hex.EncodeToString(s.SpanID[:]), s.Flags)
+}
+
+// ParseTraceParent parses the value of traceparent header, ie: the one received by server, so that the trace could be
+// continued by ContextWithSpanContext.
+func ParseTraceParent(traceParent string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(traceParent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return sc, fmt.Errorf("invalid traceparent %s", traceParent)
+	}
+	traceID, errTrace := hex.DecodeString(parts[1])
+	spanID, errSpan := hex.DecodeString(parts[2])
+	flags, errFlags := hex.DecodeString(parts[3])
+	if errTrace != nil || errSpan != nil || errFlags != nil || len(traceID) != 16 || len(spanID) != 8 || len(flags) != 1 {
+		return sc, fmt.Errorf("invalid traceparent %s", traceParent)
+	}
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Flags = flags[0]
+	if !sc.IsValid() {
+		return sc, fmt.Errorf("invalid traceparent %s", traceParent)
+	}
+	return sc, nil
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a context carrying sc, Requests with the context are traced as children of sc.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the SpanContext carried by ctx.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	if ctx == nil {
+		return SpanContext{}, false
+	}
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+type SpanStatus int
+
+const (
+	SpanStatusUnset SpanStatus = iota
+	SpanStatusOk
+	SpanStatusError
+)
+
+// Span is a finished client span of Request.
+type Span struct {
+	Name        string
+	SpanContext SpanContext
+	// ParentSpanID is zero if the span is the root of trace.
+	ParentSpanID  [8]byte
+	Start         time.Time
+	End           time.Time
+	Attributes    map[string]interface{}
+	Status        SpanStatus
+	StatusMessage string
+}
+
+// SpanExporter exports finished spans, it must be safe for concurrent use.
+type SpanExporter interface {
+	ExportSpan(span *Span) error
+}
+
+type TracingConfig struct {
+	// Exporter exports the spans, default is DefaultSpanExporter.
+	Exporter SpanExporter
+	// SpanName returns the name of span, default is `METHOD path template`.
+	SpanName func(ctx *Context) string
+}
+
+// Tracing returns a middleware that traces Requests into DefaultSpanExporter.
+func Tracing() HandlerFunc {
+	return TracingWithConfig(TracingConfig{})
+}
+
+// TracingWithConfig returns a middleware that starts a client span for the pending handlers, which continues the trace
+// carried by Context, see ContextWithSpanContext, or starts a new trace. The span is propagated by traceparent and
+// tracestate headers, and finished with the status and error of Response.
+//
+// A span is started on every run, so using it after Retry traces every attempt, rather than the Request as a whole.
+func TracingWithConfig(config TracingConfig) HandlerFunc {
+	spanName := config.SpanName
+	if spanName == nil {
+		spanName = func(ctx *Context) string {
+			return strings.TrimSpace(ctx.Method + " /" + ctx.Request.Path)
+		}
+	}
+	return func(ctx *Context) {
+		exporter := config.Exporter
+		if exporter == nil {
+			exporter = DefaultSpanExporter
+		}
+		start := time.Now()
+		span := &Span{Name: spanName(ctx), Start: start}
+		sc := SpanContext{Flags: traceFlagSampled}
+		if parent, ok := SpanContextFromContext(ctx.Context); ok {
+			sc.TraceID, sc.Flags, sc.TraceState = parent.TraceID, parent.Flags, parent.TraceState
+			span.ParentSpanID = parent.SpanID
+		} else {
+			_, _ = rand.Read(sc.TraceID[:])
+		}
+		_, _ = rand.Read(sc.SpanID[:])
+		span.SpanContext = sc
+
+		headers := map[string]string{TraceParentHeader: sc.TraceParent()}
+		if sc.TraceState != "" {
+			headers[TraceStateHeader] = sc.TraceState
+		}
+		ctx.Request.WithHeaders(headers)
+		if ctx.Context != nil {
+			// the Requests sent by pending handlers are children of current span
+			parent := ctx.Context
+			ctx.Context = ContextWithSpanContext(parent, sc)
+			defer func() {
+				ctx.Context = parent
+			}()
+		}
+
+		ctx.Next()
+
+		param := newLogFormatterParams(ctx, start)
+		span.End = param.Timestamp
+		span.Attributes = map[string]interface{}{
+			"http.request.method": param.Method,
+			"server.address":      param.Host,
+			"url.template":        "/" + param.PathTemplate,
+			"url.path":            "/" + param.Path,
+		}
+		if attempt := ctx.Attempt(); attempt > 1 {
+			span.Attributes["http.request.resend_count"] = attempt - 1
+		}
+		if param.StatusCode != 0 {
+			span.Attributes["http.response.status_code"] = param.StatusCode
+		}
+		if param.ErrMessage != "" {
+			span.Status, span.StatusMessage = SpanStatusError, param.ErrMessage
+			span.Attributes["error.type"] = param.ErrKind
+		}
+		if exporter != nil && sc.Flags&traceFlagSampled != 0 {
+			_ = exporter.ExportSpan(span)
+		}
+	}
+}
+
+// InMemoryExporter keeps the exported spans in memory, it is meant for tests.
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (e *InMemoryExporter) ExportSpan(span *Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+	return nil
+}
+
+// Spans returns the exported spans in the order they are finished.
+func (e *InMemoryExporter) Spans() []*Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*Span(nil), e.spans...)
+}
+
+// Reset drops the exported spans.
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// OTLPFileExporter appends spans into a file in OTLP/JSON format, every line is an ExportTraceServiceRequest with one
+// span, which could be read by the file receiver of OpenTelemetry Collector.
+type OTLPFileExporter struct {
+	mu          sync.Mutex
+	file        *os.File
+	serviceName string
+}
+
+// NewOTLPFileExporter opens path for appending, serviceName is the service.name of resource.
+func NewOTLPFileExporter(path, serviceName string) (*OTLPFileExporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &OTLPFileExporter{file: f, serviceName: serviceName}, nil
+}
+
+func (e *OTLPFileExporter) ExportSpan(span *Span) error {
+	b, err := json.Marshal(otlpRequest(e.serviceName, span))
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.file.Write(append(b, '\n'))
+	return err
+}
+
+// Close closes the file.
+func (e *OTLPFileExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.file.Close()
+}
+
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	TraceState        string         `json:"traceState,omitempty"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Flags             uint32         `json:"flags"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	} `json:"status"`
+}
+
+// otlpSpanKindClient is SPAN_KIND_CLIENT of OTLP.
+const otlpSpanKindClient = 3
+
+func otlpRequest(serviceName string, span *Span) map[string]interface{} {
+	s := otlpSpan{
+		TraceID:           hex.EncodeToString(span.SpanContext.TraceID[:]),
+		SpanID:            hex.EncodeToString(span.SpanContext.SpanID[:]),
+		TraceState:        span.SpanContext.TraceState,
+		Flags:             uint32(span.SpanContext.Flags),
+		Name:              span.Name,
+		Kind:              otlpSpanKindClient,
+		StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+		Attributes:        otlpAttributes(span.Attributes),
+	}
+	if span.ParentSpanID != [8]byte{} {
+		s.ParentSpanID = hex.EncodeToString(span.ParentSpanID[:])
+	}
+	s.Status.Code, s.Status.Message = int(span.Status), span.StatusMessage
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": otlpAttributes(map[string]interface{}{"service.name": serviceName}),
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]interface{}{"name": "github.com/Archer1A/go-kits/http"},
+				"spans": []otlpSpan{s},
+			}},
+		}},
+	}
+}
+
+func otlpAttributes(attrs map[string]interface{}) []otlpKeyValue {
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	kvs := make([]otlpKeyValue, 0, len(keys))
+	for _, key := range keys {
+		var value map[string]interface{}
+		switch v := attrs[key].(type) {
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		case int:
+			// int64 is encoded as string in OTLP/JSON
+			value = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		case int64:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = map[string]interface{}{"doubleValue": v}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprintf("%v", v)}
+		}
+		kvs = append(kvs, otlpKeyValue{Key: key, Value: value})
+	}
+	return kvs
+}
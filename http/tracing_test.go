@@ -0,0 +1,212 @@
+package http
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestTracing(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received[TraceParentHeader] = r.Header.Get(TraceParentHeader)
+		received[TraceStateHeader] = r.Header.Get(TraceStateHeader)
+		mu.Unlock()
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	const parentTrace = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tests := []struct {
+		name         string
+		parent       string
+		traceState   string
+		path         string
+		wantExported bool
+		wantStatus   SpanStatus
+		wantErrKind  string
+	}{
+		{name: "new trace", path: "ok", wantExported: true, wantStatus: SpanStatusUnset},
+		{name: "continued trace", parent: parentTrace, traceState: "vendor=a", path: "ok", wantExported: true},
+		{name: "error", path: "fail", wantExported: true, wantStatus: SpanStatusError, wantErrKind: "status"},
+		{name: "not sampled", parent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", path: "ok"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := &InMemoryExporter{}
+			ctx := context.Background()
+			var parent SpanContext
+			if tt.parent != "" {
+				var err error
+				if parent, err = ParseTraceParent(tt.parent); err != nil {
+					t.Fatal(err)
+				}
+				parent.TraceState = tt.traceState
+				ctx = ContextWithSpanContext(ctx, parent)
+			}
+			Req().WithHostName(server.URL).WithPath(tt.path).WithContext(ctx).
+				Use(TracingWithConfig(TracingConfig{Exporter: exporter})).
+				Get(&DefaultResponse{})
+
+			mu.Lock()
+			sent, err := ParseTraceParent(received[TraceParentHeader])
+			sentState := received[TraceStateHeader]
+			mu.Unlock()
+			if err != nil {
+				t.Fatalf("traceparent sent = %v", err)
+			}
+			if tt.parent != "" && (sent.TraceID != parent.TraceID || sent.SpanID == parent.SpanID || sent.Flags != parent.Flags) {
+				t.Errorf("traceparent sent = %s, want child of %s", sent.TraceParent(), tt.parent)
+			}
+			if sentState != tt.traceState {
+				t.Errorf("tracestate sent = %s, want %s", sentState, tt.traceState)
+			}
+
+			spans := exporter.Spans()
+			if !tt.wantExported {
+				if len(spans) != 0 {
+					t.Errorf("Tracing() exported %d spans, want none", len(spans))
+				}
+				return
+			}
+			if len(spans) != 1 {
+				t.Fatalf("Tracing() exported %d spans, want 1", len(spans))
+			}
+			span := spans[0]
+			if span.SpanContext.TraceParent() != sent.TraceParent() {
+				t.Errorf("span = %s, want %s", span.SpanContext.TraceParent(), sent.TraceParent())
+			}
+			if span.ParentSpanID != parent.SpanID {
+				t.Errorf("span parent = %x, want %x", span.ParentSpanID, parent.SpanID)
+			}
+			if span.Name != "GET /"+tt.path || span.Attributes["url.template"] != "/"+tt.path {
+				t.Errorf("span name = %s, attributes = %v", span.Name, span.Attributes)
+			}
+			if span.Status != tt.wantStatus || (tt.wantErrKind != "" && span.Attributes["error.type"] != tt.wantErrKind) {
+				t.Errorf("span status = %v, attributes = %v", span.Status, span.Attributes)
+			}
+		})
+	}
+}
+
+func TestTracingNested(t *testing.T) {
+	exporter := &InMemoryExporter{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// a middleware sending another Request, ie: fetching a token, is traced as child
+	fetchToken := func(ctx *Context) {
+		Req().WithHostName(server.URL).WithPath("token").WithContext(ctx.Context).
+			Use(TracingWithConfig(TracingConfig{Exporter: exporter})).Get(&DefaultResponse{})
+		ctx.Next()
+	}
+	Req().WithHostName(server.URL).WithPath("api").
+		Use(TracingWithConfig(TracingConfig{Exporter: exporter}), fetchToken).Get(&DefaultResponse{})
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("Tracing() exported %d spans, want 2", len(spans))
+	}
+	child, parent := spans[0], spans[1]
+	if child.SpanContext.TraceID != parent.SpanContext.TraceID || child.ParentSpanID != parent.SpanContext.SpanID {
+		t.Errorf("span %s is not child of %s", child.SpanContext.TraceParent(), parent.SpanContext.TraceParent())
+	}
+}
+
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "valid", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "future version", value: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{name: "extra field", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", wantErr: true},
+		{name: "invalid version", value: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", wantErr: true},
+		{name: "zero trace id", value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", wantErr: true},
+		{name: "short span id", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa-01", wantErr: true},
+		{name: "empty", value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := ParseTraceParent(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTraceParent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// the version is always written as 00
+			if err == nil && sc.TraceParent() != "00-"+tt.value[3:55] {
+				t.Errorf("ParseTraceParent() = %s, want %s", sc.TraceParent(), tt.value)
+			}
+		})
+	}
+}
+
+func TestOTLPFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exporter, err := NewOTLPFileExporter(path, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	for i := 0; i < 2; i++ {
+		Req().WithHostName(server.URL).WithPath("/users/{id}").PathParam("id", i).
+			Use(TracingWithConfig(TracingConfig{Exporter: exporter})).Get(&DefaultResponse{})
+	}
+	if err = exporter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("OTLPFileExporter wrote %d lines, want 2", len(lines))
+	}
+	var got struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []otlpKeyValue `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Spans []otlpSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err = json.Unmarshal([]byte(lines[1]), &got); err != nil {
+		t.Fatal(err)
+	}
+	resource := got.ResourceSpans[0].Resource.Attributes[0]
+	if resource.Key != "service.name" || resource.Value["stringValue"] != "orders" {
+		t.Errorf("resource = %v, want service.name orders", resource)
+	}
+	span := got.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if traceID, _ := hex.DecodeString(span.TraceID); len(traceID) != 16 || span.Kind != otlpSpanKindClient || span.Name != "GET /users/{id}" {
+		t.Errorf("span = %+v", span)
+	}
+	if span.Status.Code != int(SpanStatusError) {
+		t.Errorf("span status = %+v, want error", span.Status)
+	}
+	attrs := map[string]map[string]interface{}{}
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["http.response.status_code"]["intValue"] != "404" || attrs["url.path"]["stringValue"] != "/users/1" {
+		t.Errorf("span attributes = %v", attrs)
+	}
+}
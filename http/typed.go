@@ -0,0 +1,91 @@
+package http
+
+import (
+	"net/http"
+)
+
+// TypedResponse is a Response which decodes response body into Data.
+type TypedResponse[T any] struct {
+	Data T
+	err  error
+	raw  *http.Response
+}
+
+func (t *TypedResponse[T]) Error() error {
+	return t.err
+}
+
+func (t *TypedResponse[T]) ErrorSave(e error) {
+	t.err = e
+}
+
+func (t *TypedResponse[T]) SetRaw(raw *http.Response) {
+	t.raw = raw
+}
+
+func (t *TypedResponse[T]) HttpResponse() *http.Response {
+	return t.raw
+}
+
+func (t *TypedResponse[T]) Target() interface{} {
+	return &t.Data
+}
+
+// Do executes the Request using the given HTTP method, and decodes response body into T with the resolver
+// of Accept type. The zero value of T is returned if any error occurs.
+func Do[T any](req *Request, method string) (T, *http.Response, error) {
+	rsp := &TypedResponse[T]{}
+	switch method {
+	case http.MethodGet:
+		req.Get(rsp)
+	case http.MethodPost:
+		req.Post(rsp)
+	case http.MethodPut:
+		req.Put(rsp)
+	case http.MethodPatch:
+		req.Patch(rsp)
+	case http.MethodDelete:
+		req.Delete(rsp)
+	default:
+		req.ctx.Method = method
+		req.do(rsp)
+	}
+	if rsp.err != nil {
+		var zero T
+		return zero, rsp.raw, rsp.err
+	}
+	return rsp.Data, rsp.raw, nil
+}
+
+// GetJSON executes the Request using HTTP Get, and decodes JSON response body into T.
+func GetJSON[T any](req *Request) (T, *http.Response, error) {
+	return Do[T](req.Accept(ContentTypeJson), http.MethodGet)
+}
+
+// PostJSON executes the Request using HTTP Post, and decodes JSON response body into T.
+func PostJSON[T any](req *Request) (T, *http.Response, error) {
+	return Do[T](req.Accept(ContentTypeJson), http.MethodPost)
+}
+
+// PutJSON executes the Request using HTTP Put, and decodes JSON response body into T.
+func PutJSON[T any](req *Request) (T, *http.Response, error) {
+	return Do[T](req.Accept(ContentTypeJson), http.MethodPut)
+}
+
+// PatchJSON executes the Request using HTTP Patch, and decodes JSON response body into T.
+func PatchJSON[T any](req *Request) (T, *http.Response, error) {
+	return Do[T](req.Accept(ContentTypeJson), http.MethodPatch)
+}
+
+// DeleteJSON executes the Request using HTTP Delete, and decodes JSON response body into T.
+func DeleteJSON[T any](req *Request) (T, *http.Response, error) {
+	return Do[T](req.Accept(ContentTypeJson), http.MethodDelete)
+}
+
+// GetNDJSON executes the Request using HTTP Get, and calls fn with every value decoded from the NDJSON response body
+// while it is being read.
+func GetNDJSON[T any](req *Request, fn func(T) error) (*http.Response, error) {
+	rsp := &DefaultResponse{Data: fn}
+	req.Accept(ContentTypeNDJson).Get(rsp)
+	return rsp.HttpResponse(), rsp.Error()
+}
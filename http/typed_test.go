@@ -0,0 +1,103 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestTypedJSON(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			body, _ := ioutil.ReadAll(r.Body)
+			_, _ = w.Write(body)
+		case "/user":
+			_, _ = w.Write([]byte(`{"name": "abc", "age": 18}`))
+		case "/users":
+			_, _ = w.Write([]byte(`[{"name": "abc"}, {"name": "def"}]`))
+		default:
+			_, _ = w.Write([]byte(`not json`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		do      func() (interface{}, *http.Response, error)
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name: "get struct",
+			do: func() (interface{}, *http.Response, error) {
+				return GetJSON[user](Req().WithHostName(server.URL).WithPath("user"))
+			},
+			want: user{Name: "abc", Age: 18},
+		},
+		{
+			name: "get slice",
+			do: func() (interface{}, *http.Response, error) {
+				return GetJSON[[]user](Req().WithHostName(server.URL).WithPath("users"))
+			},
+			want: []user{{Name: "abc"}, {Name: "def"}},
+		},
+		{
+			name: "post",
+			do: func() (interface{}, *http.Response, error) {
+				return PostJSON[user](Req().WithHostName(server.URL).WithPath("echo").WithBody(user{Name: "abc"}))
+			},
+			want: user{Name: "abc"},
+		},
+		{
+			name: "put map",
+			do: func() (interface{}, *http.Response, error) {
+				return PutJSON[map[string]int](Req().WithHostName(server.URL).WithPath("echo").WithBody(map[string]int{"a": 1}))
+			},
+			want: map[string]int{"a": 1},
+		},
+		{
+			name: "patch",
+			do: func() (interface{}, *http.Response, error) {
+				return PatchJSON[user](Req().WithHostName(server.URL).WithPath("echo").WithBody(user{Age: 1}))
+			},
+			want: user{Age: 1},
+		},
+		{
+			name: "delete",
+			do: func() (interface{}, *http.Response, error) {
+				return DeleteJSON[*user](Req().WithHostName(server.URL).WithPath("user"))
+			},
+			want: &user{Name: "abc", Age: 18},
+		},
+		{
+			name: "malformed",
+			do: func() (interface{}, *http.Response, error) {
+				return GetJSON[user](Req().WithHostName(server.URL).WithPath("malformed"))
+			},
+			want:    user{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, raw, err := tt.do()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Do() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if raw == nil || raw.StatusCode != http.StatusOK {
+				t.Errorf("Do() raw = %v", raw)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Do() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
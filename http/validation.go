@@ -0,0 +1,405 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// validationRules are the attributes of form and query tags which are only checked by Validate, rather than
+// changing how fields are encoded.
+var validationRules = map[string]bool{
+	"min": true, "max": true, "len": true, "oneof": true, attrPattern: true,
+	"eqfield": true, "nefield": true, "gtfield": true, "gtefield": true, "ltfield": true, "ltefield": true,
+	"required_with": true, "required_without": true,
+}
+
+// FieldError is a field that fails a validation rule.
+type FieldError struct {
+	// Field is the path of the field, ie: Filter.Owner or Items[0].Name.
+	Field string
+	// Rule is the name of the failed rule, ie: required, min or gtfield.
+	Rule string
+	// Param is the parameter of the rule, ie: 10 of min=10.
+	Param string
+	Value interface{}
+}
+
+func (e *FieldError) Error() string {
+	if e.Param == "" {
+		return fmt.Sprintf("field `%s` fails rule %s", e.Field, e.Rule)
+	}
+	return fmt.Sprintf("field `%s` fails rule %s=%s", e.Field, e.Rule, e.Param)
+}
+
+// ValidationErrors lists every field that fails validation. It is saved into Response before the Request is sent.
+type ValidationErrors []*FieldError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(v))
+	for _, e := range v {
+		msgs = append(msgs, e.Error())
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Validate checks v against the rules in `form` tags of its fields, and returns ValidationErrors listing every
+// failing field. The rules are attributes of the tag besides the key, so the tag which encodes the field validates it
+// as well, ie: `form:"name,required,min=2"`, and Request validates Query by `query` tags and Body by `form` tags:
+//
+//   - required: the field is not zero
+//   - omitempty: the other rules are skipped if the field is zero
+//   - min=n, max=n: the number is not less/greater than n, or the length of string, slice or map
+//   - len=n: the length of string, slice or map is n, strings are measured in runes
+//   - oneof=a|b|c: the field is one of the values
+//   - pattern=regex: the string matches regex, it takes the rest of the tag, so it should be the last attribute
+//   - eqfield, nefield, gtfield, gtefield, ltfield, ltefield=Other: comparison with another field of the same struct,
+//     numbers, strings and time.Time are supported
+//   - required_with=Other, required_without=Other: the field is required if Other is not zero, or zero
+//
+// Nil pointers are skipped unless they are required. Nested structs, and structs in slices and maps are validated too.
+// Invalid rules and unknown attributes are returned as error rather than ValidationErrors. Requests ignore unknown
+// attributes, only invalid rules fail them.
+func Validate(v interface{}) error {
+	return validate(v, formTagName, true)
+}
+
+// validate validates v by the rules in tagName tags, unknown attributes are reported only if strict.
+func validate(v interface{}, tagName string, strict bool) error {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil
+	}
+	validator := &structValidator{tagName: tagName, strict: strict}
+	if err := validator.validateStruct(val, ""); err != nil {
+		return err
+	}
+	if len(validator.errs) > 0 {
+		return validator.errs
+	}
+	return nil
+}
+
+type structValidator struct {
+	tagName string
+	strict  bool
+	errs    ValidationErrors
+}
+
+type validateRule struct {
+	name  string
+	param string
+}
+
+func (s *structValidator) validateStruct(val reflect.Value, path string) error {
+	typ := val.Type()
+	for i := 0; i < val.NumField(); i++ {
+		structField := typ.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+		fieldPath := structField.Name
+		if path != "" {
+			fieldPath = path + "." + fieldPath
+		}
+		if structField.Anonymous {
+			fieldPath = path
+		}
+		rules, err := s.rules(structField)
+		if err != nil {
+			return fmt.Errorf("invalid validation rule of field `%s`: %v", fieldPath, err)
+		}
+		if err = s.validateField(val, val.Field(i), fieldPath, rules); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *structValidator) rules(structField reflect.StructField) ([]validateRule, error) {
+	if tag := structField.Tag.Get(s.tagName); tag == "" || tag == "-" {
+		return nil, nil
+	}
+	var rules []validateRule
+	for _, rule := range parseFormTag(structField, s.tagName).rules {
+		if rule.name != attrRequired && rule.name != attrOmitEmpty && !validationRules[rule.name] {
+			if s.strict {
+				return nil, fmt.Errorf("unknown attribute %s", rule.name)
+			}
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (s *structValidator) validateField(parent, field reflect.Value, path string, rules []validateRule) error {
+	// only nil pointers are zero, as the same as structToForm
+	isPtr, isNil := field.Kind() == reflect.Ptr, false
+	for field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface {
+		if field.IsNil() {
+			isNil = true
+			break
+		}
+		field = field.Elem()
+	}
+	isZero := isNil || !isPtr && field.IsZero()
+
+	for _, rule := range rules {
+		var ok bool
+		var err error
+		switch rule.name {
+		case "required":
+			ok = !isZero
+		case "omitempty":
+			if isZero {
+				return nil
+			}
+			continue
+		case "required_with", "required_without":
+			var other reflect.Value
+			if other, err = siblingField(parent, rule.param); err == nil {
+				otherZero := isZeroValue(other)
+				ok = !isZero || (rule.name == "required_with" && otherZero) || (rule.name == "required_without" && !otherZero)
+			}
+		default:
+			if isNil {
+				continue
+			}
+			ok, err = checkRule(parent, field, rule)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid validation rule %s of field `%s`: %v", rule.name, path, err)
+		}
+		if !ok {
+			var value interface{}
+			if !isNil {
+				value = field.Interface()
+			}
+			s.errs = append(s.errs, &FieldError{Field: path, Rule: rule.name, Param: rule.param, Value: value})
+			if rule.name == "required" || rule.name == "required_with" || rule.name == "required_without" {
+				// the other rules make no sense for a missing field
+				return nil
+			}
+		}
+	}
+	if isNil || isFormScalar(field) {
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.Struct:
+		return s.validateStruct(field, path)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < field.Len(); i++ {
+			if err := s.validateNested(field.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, key := range field.MapKeys() {
+			if err := s.validateNested(field.MapIndex(key), fmt.Sprintf("%s[%v]", path, key.Interface())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *structValidator) validateNested(elem reflect.Value, path string) error {
+	for elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Interface {
+		if elem.IsNil() {
+			return nil
+		}
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct || isFormScalar(elem) {
+		return nil
+	}
+	return s.validateStruct(elem, path)
+}
+
+func checkRule(parent, field reflect.Value, rule validateRule) (bool, error) {
+	switch rule.name {
+	case "min", "max", "len":
+		limit, err := strconv.ParseFloat(rule.param, 64)
+		if err != nil {
+			return false, err
+		}
+		n, err := measure(field, rule.name == "len")
+		if err != nil {
+			return false, err
+		}
+		switch rule.name {
+		case "min":
+			return n >= limit, nil
+		case "max":
+			return n <= limit, nil
+		default:
+			return n == limit, nil
+		}
+	case "oneof":
+		value := fmt.Sprintf("%v", field.Interface())
+		for _, option := range strings.Split(rule.param, "|") {
+			if value == option {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "pattern":
+		if field.Kind() != reflect.String {
+			return false, fmt.Errorf("pattern on %s", field.Type().String())
+		}
+		re, err := compilePattern(rule.param)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(field.String()), nil
+	default:
+		other, err := siblingField(parent, rule.param)
+		if err != nil {
+			return false, err
+		}
+		for other.Kind() == reflect.Ptr || other.Kind() == reflect.Interface {
+			if other.IsNil() {
+				// nothing to compare with
+				return true, nil
+			}
+			other = other.Elem()
+		}
+		cmp, err := compareValues(field, other)
+		if err != nil {
+			return false, err
+		}
+		switch rule.name {
+		case "eqfield":
+			return cmp == 0, nil
+		case "nefield":
+			return cmp != 0, nil
+		case "gtfield":
+			return cmp > 0, nil
+		case "gtefield":
+			return cmp >= 0, nil
+		case "ltfield":
+			return cmp < 0, nil
+		default:
+			return cmp <= 0, nil
+		}
+	}
+}
+
+// measure returns the number itself, or the length of string, slice or map.
+func measure(field reflect.Value, length bool) (float64, error) {
+	switch field.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(field.String())), nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(field.Len()), nil
+	}
+	if !length {
+		switch field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return float64(field.Int()), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return float64(field.Uint()), nil
+		case reflect.Float32, reflect.Float64:
+			return field.Float(), nil
+		}
+	}
+	return 0, fmt.Errorf("could not measure %s", field.Type().String())
+}
+
+func compareValues(a, b reflect.Value) (int, error) {
+	if a.Type() == timeType && b.Type() == timeType {
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time)), nil
+	}
+	if a.Kind() == reflect.String && b.Kind() == reflect.String {
+		return strings.Compare(a.String(), b.String()), nil
+	}
+	x, errA := measure(a, false)
+	y, errB := measure(b, false)
+	if errA != nil || errB != nil || a.Kind() == reflect.String || b.Kind() == reflect.String {
+		return 0, fmt.Errorf("could not compare %s with %s", a.Type().String(), b.Type().String())
+	}
+	switch {
+	case x < y:
+		return -1, nil
+	case x > y:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+func siblingField(parent reflect.Value, name string) (reflect.Value, error) {
+	field := parent.FieldByName(name)
+	if !field.IsValid() {
+		return reflect.Value{}, fmt.Errorf("no field %s in %s", name, parent.Type().String())
+	}
+	return field, nil
+}
+
+func isZeroValue(val reflect.Value) bool {
+	return !val.IsValid() || val.IsZero()
+}
+
+var patternCache sync.Map
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patternCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patternCache.Store(pattern, re)
+	return re, nil
+}
+
+// validateRequest validates Query and Body of Request before it is sent, the errors of both are merged.
+func validateRequest(r *Request) error {
+	var errs ValidationErrors
+	for _, part := range []struct {
+		v       interface{}
+		tagName string
+	}{{r.Query, queryTagName}, {requestBodyFields(r.Body), formTagName}} {
+		err := validate(part.v, part.tagName, false)
+		var validationErrs ValidationErrors
+		if errors.As(err, &validationErrs) {
+			errs = append(errs, validationErrs...)
+		} else if err != nil {
+			return err
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// requestBodyFields returns the part of body that could be validated.
+func requestBodyFields(body interface{}) interface{} {
+	switch b := body.(type) {
+	case *Multipart:
+		return b.Fields
+	case Multipart:
+		return b.Fields
+	case io.Reader:
+		return nil
+	default:
+		return body
+	}
+}
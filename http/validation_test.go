@@ -0,0 +1,222 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type orderItem struct {
+	SKU      string `form:",required,pattern=^[A-Z]{3}-[0-9]+$"`
+	Quantity int    `form:",min=1,max=99"`
+}
+
+type order struct {
+	ID       string            `form:",len=8"`
+	Status   string            `form:",oneof=new|paid|shipped"`
+	Note     *string           `form:",max=10"`
+	Coupon   string            `form:",omitempty,len=6"`
+	Items    []orderItem       `form:",min=1"`
+	Labels   map[string]string `form:",max=2"`
+	From     time.Time
+	To       time.Time `form:",gtfield=From"`
+	Email    string
+	Phone    string `form:",required_without=Email"`
+	Password string
+	Confirm  string `form:",eqfield=Password"`
+	Priority *int   `form:",required"`
+}
+
+func validOrder() order {
+	priority := 1
+	return order{
+		ID:       "A1234567",
+		Status:   "paid",
+		Items:    []orderItem{{SKU: "ABC-1", Quantity: 1}},
+		From:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		Email:    "bob@example.com",
+		Password: "secret",
+		Confirm:  "secret",
+		Priority: &priority,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	longNote := "a note which is too long"
+	tests := []struct {
+		name    string
+		modify  func(o *order)
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "valid",
+			modify: func(o *order) {},
+		},
+		{
+			name: "every failing field",
+			modify: func(o *order) {
+				o.ID = "short"
+				o.Status = "lost"
+				o.Note = &longNote
+				o.Coupon = "abc"
+				o.Items = []orderItem{{SKU: "abc", Quantity: 0}, {Quantity: 100}}
+				o.Labels = map[string]string{"a": "1", "b": "2", "c": "3"}
+				o.To = o.From
+				o.Email = ""
+				o.Confirm = "other"
+			},
+			want: []string{
+				"ID:len", "Status:oneof", "Note:max", "Coupon:len", "Items[0].SKU:pattern", "Items[0].Quantity:min",
+				"Items[1].SKU:required", "Items[1].Quantity:max", "Labels:max", "To:gtfield", "Phone:required_without",
+				"Confirm:eqfield",
+			},
+		},
+		{
+			name: "empty items",
+			modify: func(o *order) {
+				o.Items = nil
+			},
+			want: []string{"Items:min"},
+		},
+		{
+			name: "pointer to zero",
+			modify: func(o *order) {
+				*o.Priority = 0
+			},
+		},
+		{
+			name: "nil pointer",
+			modify: func(o *order) {
+				o.Priority = nil
+			},
+			want: []string{"Priority:required"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := validOrder()
+			tt.modify(&o)
+			err := Validate(&o)
+			var errs ValidationErrors
+			if err != nil && !errors.As(err, &errs) {
+				t.Fatalf("Validate() error = %v, want ValidationErrors", err)
+			}
+			var got []string
+			for _, e := range errs {
+				got = append(got, e.Field+":"+e.Rule)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateInvalidRule(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{name: "unknown rule", v: struct {
+			Name string `form:",email"`
+		}{}},
+		{name: "invalid pattern", v: struct {
+			Name string `form:",pattern=[a-"`
+		}{Name: "a"}},
+		{name: "missing field", v: struct {
+			Name string `form:",eqfield=Other"`
+		}{}},
+		{name: "unmeasurable", v: struct {
+			Done bool `form:",min=1"`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.v)
+			var errs ValidationErrors
+			if err == nil || errors.As(err, &errs) {
+				t.Errorf("Validate() error = %v, want invalid rule", err)
+			}
+		})
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer server.Close()
+
+	type search struct {
+		Keyword string `query:"q,required,string"` // unknown attributes are ignored by Request
+		Limit   int    `query:"limit,max=100"`
+	}
+	type signup struct {
+		Name string `form:"name,required,min=2"`
+		Age  int    `form:"age,min=18"`
+	}
+	tests := []struct {
+		name        string
+		query       interface{}
+		body        interface{}
+		contentType string
+		want        []string
+	}{
+		{
+			name:  "query and json body",
+			query: search{Limit: 500},
+			body:  &signup{Name: "a", Age: 20},
+			want:  []string{"Keyword:required", "Limit:max", "Name:min"},
+		},
+		{
+			name:        "multipart fields",
+			body:        &Multipart{Fields: signup{Age: 3}},
+			contentType: ContentTypeMultipart,
+			want:        []string{"Name:required", "Age:min"},
+		},
+		{
+			name:  "valid",
+			query: search{Keyword: "go"},
+			body:  signup{Name: "bob", Age: 30},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&hits, 0)
+			req := Req().WithHostName(server.URL).WithBody(tt.body)
+			if tt.query != nil {
+				req.WithQueries(tt.query)
+			}
+			if tt.contentType != "" {
+				req.ContentType(tt.contentType)
+			}
+			rsp := &DefaultResponse{}
+			req.Post(rsp)
+			var errs ValidationErrors
+			if rsp.Error() != nil && !errors.As(rsp.Error(), &errs) {
+				t.Fatalf("Post() error = %v, want ValidationErrors", rsp.Error())
+			}
+			var got []string
+			for _, e := range errs {
+				got = append(got, e.Field+":"+e.Rule)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Post() got = %v, want %v", got, tt.want)
+			}
+			wantHits := int32(0)
+			if tt.want == nil {
+				wantHits = 1
+			}
+			if got := atomic.LoadInt32(&hits); got != wantHits {
+				t.Errorf("Post() hits = %d, want %d", got, wantHits)
+			}
+		})
+	}
+}